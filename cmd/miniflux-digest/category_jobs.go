@@ -0,0 +1,135 @@
+package main
+
+import (
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+
+	"miniflux-digest/internal/app"
+	"miniflux-digest/internal/config"
+	"miniflux-digest/internal/processor"
+)
+
+const CategorySyncInterval = 15 * time.Minute
+
+type categoryJob struct {
+	job      gocron.Job
+	title    string
+	schedule string
+}
+
+// categoryJobs keeps one scheduled digest job per Miniflux category, in sync
+// with the categories that currently exist on the server.
+type categoryJobs struct {
+	application *app.App
+	scheduler   gocron.Scheduler
+	jitter      func() time.Duration
+
+	mu   sync.Mutex
+	jobs map[int64]*categoryJob
+}
+
+func newCategoryJobs(application *app.App, scheduler gocron.Scheduler) *categoryJobs {
+	return &categoryJobs{
+		application: application,
+		scheduler:   scheduler,
+		jitter: func() time.Duration {
+			return time.Duration(rand.Intn(JitterSeconds)) * time.Second
+		},
+		jobs: make(map[int64]*categoryJob),
+	}
+}
+
+func (c *categoryJobs) runDigest(categoryID int64) {
+	time.Sleep(c.jitter())
+
+	rawData, err := c.application.MinifluxClientService.FetchRawCategoryData(categoryID)
+	if err != nil {
+		log.Printf("Error fetching data for category %d: %v", categoryID, err)
+		return
+	}
+
+	processor.CategoryDigestJob(c.application, rawData)
+}
+
+func (c *categoryJobs) remove(categoryID int64) {
+	existing := c.jobs[categoryID]
+	if err := c.scheduler.RemoveJob(existing.job.ID()); err != nil {
+		log.Printf("Error removing job for category '%s': %v", existing.title, err)
+	}
+	delete(c.jobs, categoryID)
+}
+
+// sync registers a job for every new category, re-registers jobs whose
+// schedule changed and removes jobs for categories that no longer exist.
+func (c *categoryJobs) sync() {
+	categories, err := c.application.MinifluxClientService.Categories()
+	if err != nil {
+		log.Printf("Error fetching categories: %v", err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := make(map[int64]bool, len(categories))
+	for _, category := range categories {
+		seen[category.ID] = true
+		schedule := c.application.Config.Digest.ForCategory(category.ID, category.Title).Schedule
+
+		if existing, ok := c.jobs[category.ID]; ok {
+			if existing.schedule == schedule {
+				continue
+			}
+			c.remove(category.ID)
+		}
+
+		if !config.IsValidGocronSchedule(schedule) {
+			log.Printf("Error scheduling category '%s': invalid schedule %q", category.Title, schedule)
+			continue
+		}
+
+		job, err := c.scheduler.NewJob(
+			gocron.CronJob(schedule, true),
+			gocron.NewTask(c.runDigest, category.ID),
+			gocron.WithName(category.Title),
+			gocron.WithSingletonMode(gocron.LimitModeReschedule),
+		)
+		if err != nil {
+			log.Printf("Error creating job for category '%s': %v", category.Title, err)
+			continue
+		}
+
+		c.jobs[category.ID] = &categoryJob{job: job, title: category.Title, schedule: schedule}
+		log.Printf("Scheduled digest for category '%s' with schedule %q", category.Title, schedule)
+	}
+
+	for categoryID, existing := range c.jobs {
+		if !seen[categoryID] {
+			log.Printf("Removing digest job for deleted category '%s'", existing.title)
+			c.remove(categoryID)
+		}
+	}
+}
+
+func (c *categoryJobs) runAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, existing := range c.jobs {
+		if err := existing.job.RunNow(); err != nil {
+			log.Printf("Error running job for category '%s': %v", existing.title, err)
+		}
+	}
+}
+
+func registerCategorySyncJob(jobs *categoryJobs, scheduler gocron.Scheduler) {
+	_, err := scheduler.NewJob(gocron.DurationJob(CategorySyncInterval), gocron.NewTask(jobs.sync))
+
+	if err != nil {
+		log.Fatalf("Error creating job: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/app"
+	"miniflux-digest/internal/config"
+	"miniflux-digest/internal/digest"
+	"miniflux-digest/internal/models"
+	"miniflux-digest/internal/testutil"
+)
+
+func newTestScheduler(t *testing.T) gocron.Scheduler {
+	t.Helper()
+	scheduler, err := gocron.NewScheduler()
+	if err != nil {
+		t.Fatalf("Failed to create scheduler: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := scheduler.Shutdown(); err != nil {
+			t.Logf("Failed to shut down scheduler: %v", err)
+		}
+	})
+	return scheduler
+}
+
+func TestCategoryJobs_Sync(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	categories := []*miniflux.Category{
+		{ID: 1, Title: "Security"},
+		{ID: 2, Title: "Long reads"},
+	}
+	cfg := &config.Config{Digest: config.ConfigDigest{
+		Schedule: "@weekly",
+		Categories: map[string]config.ConfigCategory{
+			"Security": {Schedule: "0 7 * * *"},
+		},
+	}}
+	application := app.NewApp(
+		app.WithConfig(cfg),
+		app.WithMinifluxClientService(&testutil.MockMinifluxClient{
+			CategoriesFunc: func() ([]*miniflux.Category, error) {
+				return categories, nil
+			},
+		}),
+	)
+	scheduler := newTestScheduler(t)
+	jobs := newCategoryJobs(application, scheduler)
+
+	jobs.sync()
+
+	if len(scheduler.Jobs()) != 2 {
+		t.Fatalf("Expected 2 scheduled jobs, got %d", len(scheduler.Jobs()))
+	}
+	if jobs.jobs[1].schedule != "0 7 * * *" {
+		t.Errorf("Expected Security to use its own schedule, got %q", jobs.jobs[1].schedule)
+	}
+	if jobs.jobs[2].schedule != "@weekly" {
+		t.Errorf("Expected Long reads to use the global schedule, got %q", jobs.jobs[2].schedule)
+	}
+
+	securityJobID := jobs.jobs[1].job.ID()
+	jobs.sync()
+
+	if jobs.jobs[1].job.ID() != securityJobID {
+		t.Error("Expected unchanged category job to be kept")
+	}
+
+	categories = []*miniflux.Category{
+		{ID: 2, Title: "Long reads"},
+		{ID: 3, Title: "Comics"},
+	}
+	cfg.Digest.Categories["Long reads"] = config.ConfigCategory{Schedule: "0 9 * * 6"}
+
+	jobs.sync()
+
+	if len(scheduler.Jobs()) != 2 {
+		t.Fatalf("Expected 2 scheduled jobs after categories changed, got %d", len(scheduler.Jobs()))
+	}
+	if _, ok := jobs.jobs[1]; ok {
+		t.Error("Expected job for deleted category to be removed")
+	}
+	if jobs.jobs[2].schedule != "0 9 * * 6" {
+		t.Errorf("Expected Long reads to be re-registered with its new schedule, got %q", jobs.jobs[2].schedule)
+	}
+	if _, ok := jobs.jobs[3]; !ok {
+		t.Error("Expected job for new category to be registered")
+	}
+}
+
+func TestCategoryJobs_RunDigest(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var fetchedID int64
+	sent := false
+	application := app.NewApp(
+		app.WithConfig(&config.Config{}),
+		app.WithMinifluxClientService(&testutil.MockMinifluxClient{
+			FetchRawCategoryDataFunc: func(categoryID int64) (*app.RawCategoryData, error) {
+				fetchedID = categoryID
+				return &app.RawCategoryData{
+					Category: &miniflux.Category{ID: categoryID, Title: "Security"},
+					Entries:  &miniflux.Entries{{ID: 1}},
+				}, nil
+			},
+		}),
+		app.WithDigestService(&testutil.MockDigestService{
+			BuildDigestDataFunc: func(category *miniflux.Category, entries *miniflux.Entries, icons map[int64]*models.FeedIcon, groupBy digest.GroupingType, minifluxHost string) *models.HTMLTemplateData {
+				return &models.HTMLTemplateData{Category: category, Entries: entries}
+			},
+		}),
+		app.WithArchiveService(&testutil.MockArchiveService{
+			MakeArchiveHTMLFunc: func(data *models.HTMLTemplateData, compress bool) (*os.File, error) {
+				return os.CreateTemp(t.TempDir(), "test-archive-*.html")
+			},
+		}),
+		app.WithEmailService(&testutil.MockEmailService{
+			SendFunc: func(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error {
+				sent = true
+				return nil
+			},
+		}),
+	)
+	jobs := newCategoryJobs(application, newTestScheduler(t))
+	jobs.jitter = func() time.Duration { return 0 }
+
+	jobs.runDigest(7)
+
+	if fetchedID != 7 {
+		t.Errorf("Expected category 7 to be fetched, got %d", fetchedID)
+	}
+	if !sent {
+		t.Error("Expected digest to be sent")
+	}
+}
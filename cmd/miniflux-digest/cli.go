@@ -0,0 +1,231 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/app"
+	"miniflux-digest/internal/config"
+	"miniflux-digest/internal/processor"
+	"miniflux-digest/internal/templates"
+)
+
+const DefaultConfigPath = "./config.yaml"
+
+type command struct {
+	name        string
+	description string
+	run         func(args []string, stdout io.Writer) error
+}
+
+func commands() []command {
+	return []command{
+		{name: "serve", description: "run the digest scheduler and internal web server (default)", run: serveCommand},
+		{name: "run-once", description: "build and send digests now, then exit (--dry-run to only report)", run: runOnceCommand},
+		{name: "list-categories", description: "list Miniflux categories and their digest schedule", run: listCategoriesCommand},
+		{name: "validate-config", description: "validate the configuration file and exit", run: validateConfigCommand},
+		{name: "preview", description: "render a digest to a local file and open it in a browser", run: previewCommand},
+	}
+}
+
+func usage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: miniflux-digest <command> [--config path] [options]")
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands() {
+		_, _ = fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.description)
+	}
+}
+
+// runCommand dispatches to a subcommand, defaulting to serve so that running
+// the binary without arguments keeps starting the daemon.
+func runCommand(args []string, stdout io.Writer) error {
+	name := "serve"
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		usage(stdout)
+		return nil
+	}
+
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			err := cmd.run(args, stdout)
+			if errors.Is(err, flag.ErrHelp) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	usage(stdout)
+	return fmt.Errorf("unknown command %q", name)
+}
+
+func newFlagSet(name string, stdout io.Writer) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stdout)
+	configPath := fs.String("config", DefaultConfigPath, "path to the configuration file")
+	return fs, configPath
+}
+
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading configuration %s: %w", path, err)
+	}
+	if err := templates.Load(cfg.Templates.Dir, cfg.BodyTemplateFiles(), cfg.Subjects()); err != nil {
+		return nil, fmt.Errorf("error loading templates: %w", err)
+	}
+	return cfg, nil
+}
+
+func serveCommand(args []string, stdout io.Writer) error {
+	fs, configPath := newFlagSet("serve", stdout)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	return serve(cfg)
+}
+
+func runOnceCommand(args []string, stdout io.Writer) error {
+	fs, configPath := newFlagSet("run-once", stdout)
+	categoryID := fs.Int64("category", 0, "only digest the Miniflux category with this ID")
+	dryRun := fs.Bool("dry-run", false, "build digests into a temporary directory without sending email or marking entries as read")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	if *dryRun {
+		return dryRunOnce(cfg, *categoryID, stdout)
+	}
+
+	application, err := initServices(cfg)
+	if err != nil {
+		return fmt.Errorf("error initializing services: %w", err)
+	}
+	defer func() {
+		if err := application.EmailService.Close(); err != nil {
+			log.Printf("Error closing email service: %v", err)
+		}
+	}()
+
+	return runOnce(application, *categoryID, nil)
+}
+
+// dryRunOnce reports the digests runOnce would send, archiving them into a
+// temporary directory.
+func dryRunOnce(cfg *config.Config, categoryID int64, stdout io.Writer) error {
+	tmpDir, err := os.MkdirTemp("", "miniflux-digest-dry-run")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+
+	application, err := initDryRunServices(cfg, tmpDir)
+	if err != nil {
+		return fmt.Errorf("error initializing services: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(stdout, "Dry run, archives are written to %s\n", tmpDir); err != nil {
+		return err
+	}
+	return runOnce(application, categoryID, stdout)
+}
+
+// runOnce digests one category, or every category when categoryID is 0. When
+// dryRun is set, a report is written to it instead of sending email.
+func runOnce(application *app.App, categoryID int64, dryRun io.Writer) error {
+	digestCategory := func(rawData *app.RawCategoryData) error {
+		if dryRun != nil {
+			return processor.DryRunCategoryDigestJob(application, rawData, dryRun)
+		}
+		processor.CategoryDigestJob(application, rawData)
+		return nil
+	}
+
+	if categoryID != 0 {
+		rawData, err := application.MinifluxClientService.FetchRawCategoryData(categoryID)
+		if err != nil {
+			return fmt.Errorf("error fetching category %d: %w", categoryID, err)
+		}
+		return digestCategory(rawData)
+	}
+
+	var errs []error
+	for rawData := range application.MinifluxClientService.StreamAllCategoryData() {
+		if err := digestCategory(rawData); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func listCategoriesCommand(args []string, stdout io.Writer) error {
+	fs, configPath := newFlagSet("list-categories", stdout)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	client := app.NewMinifluxClientWrapper(miniflux.NewClient(cfg.Miniflux.Host, cfg.Miniflux.ApiToken))
+	return listCategories(cfg, client, stdout)
+}
+
+func listCategories(cfg *config.Config, client app.MinifluxClientService, stdout io.Writer) error {
+	categories, err := client.Categories()
+	if err != nil {
+		return fmt.Errorf("error fetching categories: %w", err)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tTITLE\tINCLUDED\tSCHEDULE\tGROUP BY")
+	for _, category := range categories {
+		digestConfig := cfg.Digest.ForCategory(category.ID, category.Title)
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%t\t%s\t%s\n",
+			category.ID,
+			category.Title,
+			cfg.Digest.IncludesCategory(category.ID, category.Title),
+			digestConfig.Schedule,
+			digestConfig.GroupBy,
+		)
+	}
+	return w.Flush()
+}
+
+func validateConfigCommand(args []string, stdout io.Writer) error {
+	fs, configPath := newFlagSet("validate-config", stdout)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if _, err := loadConfig(*configPath); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(stdout, "Configuration %s is valid\n", *configPath)
+	return err
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/app"
+	"miniflux-digest/internal/config"
+	"miniflux-digest/internal/digest"
+	"miniflux-digest/internal/models"
+	"miniflux-digest/internal/testutil"
+)
+
+func writeTestConfig(t *testing.T, content string) string {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return configPath
+}
+
+func TestRunCommand_ValidateConfig(t *testing.T) {
+	validPath := writeTestConfig(t, "miniflux:\n  host: https://miniflux.example.com\n  api_token: token\ndigest:\n  email:\n    to: reader@example.com\n")
+	invalidPath := writeTestConfig(t, "miniflux:\n  host: https://miniflux.example.com\n")
+
+	var out bytes.Buffer
+	if err := runCommand([]string{"validate-config", "--config", validPath}, &out); err != nil {
+		t.Fatalf("Expected valid configuration, got: %v", err)
+	}
+	if !strings.Contains(out.String(), "is valid") {
+		t.Errorf("Expected success message, got %q", out.String())
+	}
+
+	if err := runCommand([]string{"validate-config", "--config", invalidPath}, &out); err == nil {
+		t.Error("Expected error for invalid configuration")
+	}
+
+	if err := runCommand([]string{"validate-config", "--config", filepath.Join(t.TempDir(), "missing.yaml")}, &out); err == nil {
+		t.Error("Expected error for missing configuration file")
+	}
+}
+
+func TestRunCommand_ValidateConfigTemplates(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "email.gotxt"), []byte("{{ .NoSuchField }}"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	configPath := writeTestConfig(t, "miniflux:\n  host: https://miniflux.example.com\n  api_token: token\ndigest:\n  email:\n    to: reader@example.com\ntemplates:\n  dir: "+dir+"\n")
+
+	var out bytes.Buffer
+	err := runCommand([]string{"validate-config", "--config", configPath}, &out)
+	if err == nil || !strings.Contains(err.Error(), filepath.Join(dir, "email.gotxt")) {
+		t.Errorf("Expected an error naming the broken template, got %v", err)
+	}
+}
+
+func TestRunCommand_ValidateConfigBodyTemplate(t *testing.T) {
+	body := filepath.Join(t.TempDir(), "body.gotxt")
+	if err := os.WriteFile(body, []byte("{{ .NoSuchField }}"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	configPath := writeTestConfig(t, "miniflux:\n  host: https://miniflux.example.com\n  api_token: token\ndigest:\n  email:\n    to: reader@example.com\n  categories:\n    Security:\n      email:\n        body_template_file: "+body+"\n")
+
+	var out bytes.Buffer
+	err := runCommand([]string{"validate-config", "--config", configPath}, &out)
+	if err == nil || !strings.Contains(err.Error(), body) {
+		t.Errorf("Expected an error naming the broken body template, got %v", err)
+	}
+}
+
+func TestRunCommand_ValidateConfigSubject(t *testing.T) {
+	configPath := writeTestConfig(t, "miniflux:\n  host: https://miniflux.example.com\n  api_token: token\ndigest:\n  email:\n    to: reader@example.com\n  categories:\n    Security:\n      email:\n        subject: \"{{ .Nope }}\"\n")
+
+	var out bytes.Buffer
+	err := runCommand([]string{"validate-config", "--config", configPath}, &out)
+	if err == nil || !strings.Contains(err.Error(), "{{ .Nope }}") {
+		t.Errorf("Expected an error naming the broken category subject, got %v", err)
+	}
+}
+
+func TestRunCommand_Unknown(t *testing.T) {
+	var out bytes.Buffer
+	if err := runCommand([]string{"explode"}, &out); err == nil {
+		t.Error("Expected error for unknown command")
+	}
+	if !strings.Contains(out.String(), "run-once") {
+		t.Errorf("Expected usage to list commands, got %q", out.String())
+	}
+}
+
+func TestRunCommand_Help(t *testing.T) {
+	var out bytes.Buffer
+	if err := runCommand([]string{"run-once", "-h"}, &out); err != nil {
+		t.Errorf("Expected help to succeed, got: %v", err)
+	}
+	if !strings.Contains(out.String(), "-category") {
+		t.Errorf("Expected run-once flags in help output, got %q", out.String())
+	}
+}
+
+func TestListCategories(t *testing.T) {
+	cfg := &config.Config{Digest: config.ConfigDigest{
+		Schedule:          "@weekly",
+		GroupBy:           digest.GroupingTypeDay,
+		ExcludeCategories: []string{"Podcasts"},
+		Categories: map[string]config.ConfigCategory{
+			"Security": {Schedule: "0 7 * * *"},
+		},
+	}}
+	client := &testutil.MockMinifluxClient{
+		CategoriesFunc: func() ([]*miniflux.Category, error) {
+			return []*miniflux.Category{{ID: 1, Title: "Security"}, {ID: 2, Title: "Podcasts"}}, nil
+		},
+	}
+
+	var out bytes.Buffer
+	if err := listCategories(cfg, client, &out); err != nil {
+		t.Fatalf("listCategories failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected header and 2 categories, got %q", out.String())
+	}
+	if !strings.Contains(lines[1], "Security") || !strings.Contains(lines[1], "true") || !strings.Contains(lines[1], "0 7 * * *") {
+		t.Errorf("Unexpected Security line: %q", lines[1])
+	}
+	if !strings.Contains(lines[2], "Podcasts") || !strings.Contains(lines[2], "false") || !strings.Contains(lines[2], "@weekly") {
+		t.Errorf("Unexpected Podcasts line: %q", lines[2])
+	}
+}
+
+func TestRunOnce(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var digested []int64
+	client := &testutil.MockMinifluxClient{
+		FetchRawCategoryDataFunc: func(categoryID int64) (*app.RawCategoryData, error) {
+			return &app.RawCategoryData{Category: &miniflux.Category{ID: categoryID}, Entries: &miniflux.Entries{}}, nil
+		},
+		StreamAllCategoryDataFunc: func() <-chan *app.RawCategoryData {
+			out := make(chan *app.RawCategoryData, 2)
+			out <- &app.RawCategoryData{Category: &miniflux.Category{ID: 1}, Entries: &miniflux.Entries{}}
+			out <- &app.RawCategoryData{Category: &miniflux.Category{ID: 2}, Entries: &miniflux.Entries{}}
+			close(out)
+			return out
+		},
+	}
+	application := app.NewApp(
+		app.WithConfig(&config.Config{}),
+		app.WithMinifluxClientService(client),
+		app.WithDigestService(&testutil.MockDigestService{
+			BuildDigestDataFunc: func(category *miniflux.Category, entries *miniflux.Entries, icons map[int64]*models.FeedIcon, groupBy digest.GroupingType, minifluxHost string) *models.HTMLTemplateData {
+				digested = append(digested, category.ID)
+				return &models.HTMLTemplateData{Category: category, Entries: entries}
+			},
+		}),
+	)
+
+	if err := runOnce(application, 5, nil); err != nil {
+		t.Fatalf("runOnce failed: %v", err)
+	}
+	if len(digested) != 1 || digested[0] != 5 {
+		t.Errorf("Expected only category 5 to be digested, got %v", digested)
+	}
+
+	digested = nil
+	if err := runOnce(application, 0, nil); err != nil {
+		t.Fatalf("runOnce failed: %v", err)
+	}
+	if len(digested) != 2 {
+		t.Errorf("Expected all categories to be digested, got %v", digested)
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"html/template"
 	"log"
-	"math/rand"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -17,60 +20,143 @@ import (
 	"miniflux-digest/internal/digest"
 	"miniflux-digest/internal/email"
 	"miniflux-digest/internal/llm"
+	"miniflux-digest/internal/outbox"
 	"miniflux-digest/internal/processor"
+	"miniflux-digest/internal/unsubscribe"
 )
 
 const (
 	JitterSeconds         = 30
 	ArchiveCleanupDays    = 21
 	ArchiveBasePath       = "web/miniflux-archive"
+	OutboxPath            = "web/miniflux-outbox"
+	OutboxRetryInterval   = time.Minute
+	SuppressionListPath   = "web/miniflux-suppressions"
+	CachePath             = "web/miniflux-cache"
 	HealthCheckPort       = ":8080"
 )
 
-func registerCategoryDigestJob(application *app.App, scheduler gocron.Scheduler, rawData *app.RawCategoryData) {
-	task := func(rawData *app.RawCategoryData) {
-		processor.CategoryDigestJob(application, rawData, application.Config.Digest.MarkAsRead)
-	}
+func registerArchiveCleanupJob(application *app.App, scheduler gocron.Scheduler) {
+	_, err := scheduler.NewJob(gocron.DurationJob(time.Hour*24), gocron.NewTask(func() {
+		application.ArchiveService.CleanArchive(time.Hour * 24 * ArchiveCleanupDays)
+	}))
 
-	jitter := time.Duration(rand.Intn(JitterSeconds)) * time.Second
-	startTime := time.Now().Add(1*time.Minute + jitter)
+	if err != nil {
+		log.Fatalf("Error creating job: %v", err)
+	}
+}
 
+func registerOutboxRetryJob(application *app.App, scheduler gocron.Scheduler) {
 	_, err := scheduler.NewJob(
-		gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(startTime)),
-		gocron.NewTask(task, rawData),
+		gocron.DurationJob(OutboxRetryInterval),
+		gocron.NewTask(func() {
+			processor.RetryFailedDeliveries(application)
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
 	)
+
 	if err != nil {
-		log.Printf("Error creating one-time job for category %d: %v", rawData.Category.ID, err)
+		log.Fatalf("Error creating job: %v", err)
 	}
 }
 
-func categoriesCheckJob(application *app.App, scheduler gocron.Scheduler) {
-	for rawData := range application.MinifluxClientService.StreamAllCategoryData() {
-		registerCategoryDigestJob(application, scheduler, rawData)
-	}
+type outboxStatus struct {
+	ID          string    `json:"id"`
+	CategoryID  int64     `json:"category_id"`
+	Category    string    `json:"category"`
+	Attempts    int       `json:"attempts"`
+	CreatedAt   time.Time `json:"created_at"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error"`
 }
 
-func registerCategoriesCheckJob(application *app.App, scheduler gocron.Scheduler) {
-	_, err := scheduler.NewJob(gocron.CronJob(application.Config.Digest.Schedule, true), gocron.NewTask(func() {
-		categoriesCheckJob(application, scheduler)
-	}))
+func outboxHandler(outbox app.Outbox) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deliveries, err := outbox.List()
+		if err != nil {
+			log.Printf("Error reading outbox: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
 
-	if err != nil {
-		log.Fatalf("Error creating job: %v", err)
+		statuses := make([]outboxStatus, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			statuses = append(statuses, outboxStatus{
+				ID:          delivery.ID,
+				CategoryID:  delivery.Data.Category.ID,
+				Category:    delivery.Data.Category.Title,
+				Attempts:    delivery.Attempts,
+				CreatedAt:   delivery.CreatedAt,
+				NextAttempt: delivery.NextAttempt,
+				LastError:   delivery.LastError,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(statuses); err != nil {
+			log.Printf("Error writing outbox response: %v", err)
+		}
 	}
 }
 
-func registerArchiveCleanupJob(application *app.App, scheduler gocron.Scheduler) {
-	_, err := scheduler.NewJob(gocron.DurationJob(time.Hour*24), gocron.NewTask(func() {
-		application.ArchiveService.CleanArchive(time.Hour * 24 * ArchiveCleanupDays)
-	}))
+var unsubscribeTemplate = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Unsubscribe</title></head>
+<body>
+{{if .Done}}
+<p>{{.Email}} will no longer receive this digest.</p>
+{{else}}
+<form method="post">
+<p>Stop sending this digest to {{.Email}}?</p>
+<button type="submit" name="List-Unsubscribe" value="One-Click">Unsubscribe</button>
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+// unsubscribeHandler asks for confirmation on GET, so link scanners cannot
+// unsubscribe anyone, and unsubscribes on POST, which is also what mail
+// clients send for one-click unsubscribes.
+func unsubscribeHandler(signer *unsubscribe.Signer, suppressions app.SuppressionList) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		categoryID, address, ok := signer.Verify(r.URL.Query())
+		if !ok {
+			http.Error(w, "Invalid unsubscribe link", http.StatusForbidden)
+			return
+		}
 
-	if err != nil {
-		log.Fatalf("Error creating job: %v", err)
+		done := false
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			if err := suppressions.Suppress(categoryID, address); err != nil {
+				log.Printf("Error unsubscribing %s from category %d: %v", address, categoryID, err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			log.Printf("Unsubscribed %s from category %d", address, categoryID)
+			done = true
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := unsubscribeTemplate.Execute(w, struct {
+			Email string
+			Done  bool
+		}{address, done})
+		if err != nil {
+			log.Printf("Error writing unsubscribe response: %v", err)
+		}
 	}
 }
 
-func SetupServer(archiveBasePath string) *http.ServeMux {
+// SetupServer registers the internal web server routes. The outbox and
+// unsubscribe routes are only added when outbox and signer are set.
+func SetupServer(archiveBasePath string, outbox app.Outbox, signer *unsubscribe.Signer, suppressions app.SuppressionList) *http.ServeMux {
 	mux := http.NewServeMux()
 
 	mux.HandleFunc("/healthcheck", func(w http.ResponseWriter, r *http.Request) {
@@ -80,6 +166,14 @@ func SetupServer(archiveBasePath string) *http.ServeMux {
 		}
 	})
 
+	if outbox != nil {
+		mux.HandleFunc("/outbox", outboxHandler(outbox))
+	}
+
+	if signer != nil && suppressions != nil {
+		mux.HandleFunc(unsubscribe.Path, unsubscribeHandler(signer, suppressions))
+	}
+
 	fs := http.FileServer(http.Dir(archiveBasePath))
 	mux.Handle("/archive/", http.StripPrefix("/archive/", fs))
 
@@ -101,20 +195,20 @@ func requestSanitizerMiddleware(next http.Handler) http.Handler {
 }
 
 func main() {
-	cfg, err := config.Load("./config.yaml")
-
-	if err != nil {
-		log.Fatalf("Error loading configuration: %v", err)
+	if err := runCommand(os.Args[1:], os.Stdout); err != nil {
+		log.Fatalf("Error: %v", err)
 	}
+}
 
+func serve(cfg *config.Config) error {
 	application, err := initServices(cfg)
 	if err != nil {
-		log.Fatalf("Error initializing services: %v", err)
+		return fmt.Errorf("error initializing services: %w", err)
 	}
 
 	scheduler, err := initScheduler()
 	if err != nil {
-		log.Fatalf("Error creating scheduler: %v", err)
+		return fmt.Errorf("error creating scheduler: %w", err)
 	}
 
 	defer func() {
@@ -123,15 +217,20 @@ func main() {
 		}
 	}()
 
-	registerCategoriesCheckJob(application, scheduler)
-	registerArchiveCleanupJob(application, scheduler)
+	categoryJobs := newCategoryJobs(application, scheduler)
+	categoryJobs.sync()
 
-	if application.Config.Digest.RunOnStartup {
-		go categoriesCheckJob(application, scheduler)
-	}
+	registerCategorySyncJob(categoryJobs, scheduler)
+	registerArchiveCleanupJob(application, scheduler)
+	registerOutboxRetryJob(application, scheduler)
 
 	go func() {
-		mux := SetupServer(ArchiveBasePath)
+		var signer *unsubscribe.Signer
+		if cfg.Digest.Unsubscribe.Secret != "" {
+			signer = unsubscribe.NewSigner(cfg.Digest.Host, cfg.Digest.Unsubscribe.Secret)
+		}
+
+		mux := SetupServer(ArchiveBasePath, application.Outbox, signer, application.Suppressions)
 		log.Printf("Internal web server starting on port %s", HealthCheckPort)
 
 		if err := http.ListenAndServe(HealthCheckPort, requestSanitizerMiddleware(mux)); err != nil {
@@ -141,21 +240,103 @@ func main() {
 
 	scheduler.Start()
 
+	if application.Config.Digest.RunOnStartup {
+		categoryJobs.runAll()
+	}
+
 	select {}
 }
 
-func initServices(cfg *config.Config) (*app.App, error) {
+// newLLMService creates the client of the configured ai.provider, caching its
+// responses under cacheDir when ai.cache.ttl is set. An empty cacheDir
+// disables the cache.
+func newLLMService(ai *config.ConfigAI, cacheDir string) (llm.LLMService, error) {
+	opts := []llm.Option{
+		llm.WithModel(ai.Model),
+		llm.WithMaxOutputTokens(ai.MaxOutputTokens),
+		llm.WithTimeout(ai.Timeout),
+	}
+	if ai.Temperature != nil {
+		opts = append(opts, llm.WithTemperature(*ai.Temperature))
+	}
+
+	var service llm.LLMService
+	if ai.Provider == config.AIProviderOpenAI {
+		service = llm.NewOpenAIService(ai.BaseURL, ai.ApiKey, opts...)
+	} else {
+		gemini, err := llm.NewGeminiService(ai.ApiKey, opts...)
+		if err != nil {
+			return nil, err
+		}
+		service = gemini
+	}
+
+	if ai.Cache.TTL == 0 || cacheDir == "" {
+		return service, nil
+	}
+	return llm.NewCachedService(service, filepath.Join(cacheDir, "llm"),
+		llm.WithCacheTTL(ai.Cache.TTL),
+		llm.WithCacheMaxSize(int64(ai.Cache.MaxSizeMB)<<20),
+	)
+}
+
+// newDigestService creates the digest service, keeping entry summaries under
+// cacheDir. An empty cacheDir summarizes every entry again on each run.
+func newDigestService(ai *config.ConfigAI, llmService llm.LLMService, cacheDir string) (*digest.DigestService, error) {
+	opts := []digest.DigestServiceOption{digest.WithMaxInputTokens(ai.MaxInputTokens)}
+	if ai.EntrySummaries {
+		var cache *digest.SummaryCache
+		if cacheDir != "" {
+			var err error
+			if cache, err = digest.NewSummaryCache(cacheDir); err != nil {
+				return nil, err
+			}
+		}
+		opts = append(opts, digest.WithEntrySummaries(cache))
+	}
+	return digest.NewDigestService(llmService, opts...), nil
+}
+
+func newMinifluxClient(cfg *config.Config) *app.MinifluxClientWrapper {
 	minifluxClient := miniflux.NewClient(cfg.Miniflux.Host, cfg.Miniflux.ApiToken)
-	clientWrapper := app.NewMinifluxClientWrapper(minifluxClient)
+	return app.NewMinifluxClientWrapper(
+		minifluxClient,
+		app.WithMaxEntries(cfg.Digest.MaxEntries),
+		app.WithCategoryFilter(func(category *miniflux.Category) bool {
+			return cfg.Digest.IncludesCategory(category.ID, category.Title)
+		}),
+	)
+}
+
+func initServices(cfg *config.Config) (*app.App, error) {
+	clientWrapper := newMinifluxClient(cfg)
+
+	llmService, err := newLLMService(&cfg.AI, CachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	suppressions, err := unsubscribe.NewFileSuppressionList(SuppressionListPath)
+	if err != nil {
+		return nil, err
+	}
+
+	emailSvc, err := email.NewEmailService(cfg, email.WithSuppressionList(suppressions))
+	if err != nil {
+		return nil, err
+	}
 
-	llmService, err := llm.NewGeminiService(cfg.AI.ApiKey)
+	outboxSvc, err := outbox.NewFileOutbox(OutboxPath)
+	if err != nil {
+		return nil, err
+	}
+
+	digestService, err := newDigestService(&cfg.AI, llmService, CachePath)
 	if err != nil {
 		return nil, err
 	}
 
 	archiveSvc := archive.NewArchiveService(ArchiveBasePath)
-	emailSvc := &email.EmailServiceImpl{}
-	digestService := digest.NewDigestService(llmService)
 
 	application := app.NewApp(
 		app.WithConfig(cfg),
@@ -164,11 +345,55 @@ func initServices(cfg *config.Config) (*app.App, error) {
 		app.WithMinifluxClientService(clientWrapper),
 		app.WithDigestService(digestService),
 		app.WithLLMService(llmService),
+		app.WithOutbox(outboxSvc),
+		app.WithSuppressionList(suppressions),
 	)
 
 	return application, nil
 }
 
+// initDryRunServices creates the services of a dry run, which archives into
+// archiveDir and never sends email or writes to the outbox, suppression list
+// or caches. The outbox and suppression list are only read when they exist,
+// so the report leaves out queued entries and unsubscribed recipients.
+func initDryRunServices(cfg *config.Config, archiveDir string) (*app.App, error) {
+	llmService, err := newLLMService(&cfg.AI, "")
+	if err != nil {
+		return nil, err
+	}
+
+	digestService, err := newDigestService(&cfg.AI, llmService, "")
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []app.Option{
+		app.WithConfig(cfg),
+		app.WithArchiveService(archive.NewArchiveService(archiveDir)),
+		app.WithMinifluxClientService(newMinifluxClient(cfg)),
+		app.WithDigestService(digestService),
+		app.WithLLMService(llmService),
+	}
+
+	if _, err := os.Stat(OutboxPath); err == nil {
+		outboxSvc, err := outbox.NewFileOutbox(OutboxPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, app.WithOutbox(outboxSvc))
+	}
+
+	if _, err := os.Stat(SuppressionListPath); err == nil {
+		suppressions, err := unsubscribe.NewFileSuppressionList(SuppressionListPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, app.WithSuppressionList(suppressions))
+	}
+
+	return app.NewApp(opts...), nil
+}
+
 func initScheduler() (gocron.Scheduler, error) {
 	return gocron.NewScheduler()
 }
\ No newline at end of file
@@ -1,12 +1,24 @@
 package main
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/app"
+	"miniflux-digest/internal/config"
+	"miniflux-digest/internal/llm"
+	"miniflux-digest/internal/models"
+	"miniflux-digest/internal/unsubscribe"
 )
 
 func setupTestArchive(t *testing.T) string {
@@ -38,7 +50,7 @@ func setupTestArchive(t *testing.T) string {
 func TestHealthCheckHandler(t *testing.T) {
 	req := httptest.NewRequest("GET", "/healthcheck", nil)
 	rr := httptest.NewRecorder()
-	mux := SetupServer("") // archive base path is not needed for this test
+	mux := SetupServer("", nil, nil, nil) // archive base path is not needed for this test
 	h := requestSanitizerMiddleware(mux)
 	h.ServeHTTP(rr, req)
 
@@ -56,7 +68,7 @@ func TestHealthCheckHandler(t *testing.T) {
 
 func TestServeArchiveFile_Success(t *testing.T) {
 	archiveBasePath := setupTestArchive(t)
-	mux := SetupServer(archiveBasePath)
+	mux := SetupServer(archiveBasePath, nil, nil, nil)
 
 	req := httptest.NewRequest("GET", "/archive/test-category/test-file.html", nil)
 	rr := httptest.NewRecorder()
@@ -81,7 +93,7 @@ func TestServeArchiveFile_Success(t *testing.T) {
 
 func TestServeArchiveFile_NotFound(t *testing.T) {
 	archiveBasePath := setupTestArchive(t)
-	mux := SetupServer(archiveBasePath)
+	mux := SetupServer(archiveBasePath, nil, nil, nil)
 
 	req := httptest.NewRequest("GET", "/archive/test-category/not-found.html", nil)
 	rr := httptest.NewRecorder()
@@ -96,7 +108,7 @@ func TestServeArchiveFile_NotFound(t *testing.T) {
 
 func TestServeArchiveFile_PathTraversal(t *testing.T) {
 	archiveBasePath := setupTestArchive(t)
-	mux := SetupServer(archiveBasePath)
+	mux := SetupServer(archiveBasePath, nil, nil, nil)
 
 	// Attempt to access a file outside the archive base path
 	// The http.FileServer should prevent this, resulting in a 400
@@ -113,7 +125,7 @@ func TestServeArchiveFile_PathTraversal(t *testing.T) {
 
 func TestServeArchiveFile_DirectoryRequest(t *testing.T) {
 	archiveBasePath := setupTestArchive(t)
-	mux := SetupServer(archiveBasePath)
+	mux := SetupServer(archiveBasePath, nil, nil, nil)
 
 	req := httptest.NewRequest("GET", "/archive/test-category/", nil)
 	rr := httptest.NewRecorder()
@@ -125,3 +137,156 @@ func TestServeArchiveFile_DirectoryRequest(t *testing.T) {
 			status, http.StatusNotFound)
 	}
 }
+
+func TestOutboxHandler(t *testing.T) {
+	outbox := app.NewMemoryOutbox()
+	if err := outbox.Save(&app.PendingDelivery{
+		ID:        "7-1",
+		Data:      &models.HTMLTemplateData{Category: &miniflux.Category{ID: 7, Title: "Security"}},
+		Attempts:  2,
+		LastError: "smtp down",
+	}); err != nil {
+		t.Fatalf("Failed to queue delivery: %v", err)
+	}
+
+	req := httptest.NewRequest("GET", "/outbox", nil)
+	rr := httptest.NewRecorder()
+	mux := SetupServer("", outbox, nil, nil)
+	requestSanitizerMiddleware(mux).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var statuses []outboxStatus
+	if err := json.NewDecoder(rr.Body).Decode(&statuses); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Category != "Security" || statuses[0].Attempts != 2 || statuses[0].LastError != "smtp down" {
+		t.Errorf("Unexpected outbox status: %+v", statuses)
+	}
+}
+
+func TestUnsubscribeHandler(t *testing.T) {
+	suppressions, err := unsubscribe.NewFileSuppressionList(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create suppression list: %v", err)
+	}
+	signer := unsubscribe.NewSigner("https://digest.example.com", "secret")
+	mux := SetupServer("", nil, signer, suppressions)
+
+	link, err := url.Parse(signer.URL(7, "reader@example.com"))
+	if err != nil {
+		t.Fatalf("Failed to parse unsubscribe URL: %v", err)
+	}
+	target := link.RequestURI()
+
+	serve := func(method, target string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		requestSanitizerMiddleware(mux).ServeHTTP(rr, req)
+		return rr
+	}
+	suppressed := func() bool {
+		suppressed, err := suppressions.IsSuppressed(7, "reader@example.com")
+		if err != nil {
+			t.Fatalf("IsSuppressed failed: %v", err)
+		}
+		return suppressed
+	}
+
+	if rr := serve("GET", target, nil); rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "<form") {
+		t.Errorf("Expected a confirmation form on GET, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if suppressed() {
+		t.Fatal("Expected GET not to unsubscribe")
+	}
+
+	if rr := serve("POST", strings.Replace(target, "category=7", "category=8", 1), strings.NewReader("List-Unsubscribe=One-Click")); rr.Code != http.StatusForbidden {
+		t.Errorf("Expected a tampered link to be rejected, got %d", rr.Code)
+	}
+
+	if rr := serve("POST", target, strings.NewReader("List-Unsubscribe=One-Click")); rr.Code != http.StatusOK {
+		t.Errorf("Expected one-click POST to succeed, got %d", rr.Code)
+	}
+	if !suppressed() {
+		t.Error("Expected POST to unsubscribe the recipient")
+	}
+}
+
+func TestNewLLMService(t *testing.T) {
+	service, err := newLLMService(&config.ConfigAI{Provider: config.AIProviderOpenAI, BaseURL: "http://localhost:11434/v1"}, CachePath)
+	if err != nil {
+		t.Fatalf("newLLMService failed: %v", err)
+	}
+	if _, ok := service.(*llm.OpenAIService); !ok {
+		t.Errorf("Expected an OpenAI compatible service, got %T", service)
+	}
+
+	service, err = newLLMService(&config.ConfigAI{Provider: config.AIProviderGemini}, CachePath)
+	if err != nil {
+		t.Fatalf("newLLMService failed: %v", err)
+	}
+	if _, ok := service.(*llm.GeminiService); !ok {
+		t.Errorf("Expected a Gemini service, got %T", service)
+	}
+
+	t.Chdir(t.TempDir())
+	service, err = newLLMService(&config.ConfigAI{Provider: config.AIProviderGemini, Cache: config.ConfigAICache{TTL: time.Hour}}, CachePath)
+	if err != nil {
+		t.Fatalf("newLLMService failed: %v", err)
+	}
+	if _, ok := service.(*llm.CachedService); !ok {
+		t.Errorf("Expected a cached service, got %T", service)
+	}
+	if _, err := os.Stat(filepath.Join(CachePath, "llm")); err != nil {
+		t.Errorf("Expected the LLM cache directory to be created: %v", err)
+	}
+}
+
+func TestInitDryRunServices(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cfg := &config.Config{AI: config.ConfigAI{
+		Provider:       config.AIProviderOpenAI,
+		BaseURL:        "http://localhost:11434/v1",
+		EntrySummaries: true,
+		Cache:          config.ConfigAICache{TTL: time.Hour},
+	}}
+
+	application, err := initDryRunServices(cfg, t.TempDir())
+	if err != nil {
+		t.Fatalf("initDryRunServices failed: %v", err)
+	}
+	if _, ok := application.LLMService.(*llm.CachedService); ok {
+		t.Error("Expected a dry run not to cache LLM responses")
+	}
+	if application.Suppressions != nil {
+		t.Error("Expected no suppression list when none exists")
+	}
+	if _, err := os.Stat("web"); err == nil {
+		t.Error("Expected a dry run not to create the outbox, suppression or cache directories")
+	}
+
+	suppressions, err := unsubscribe.NewFileSuppressionList(SuppressionListPath)
+	if err != nil {
+		t.Fatalf("Failed to create suppression list: %v", err)
+	}
+	if err := suppressions.Suppress(1, "reader@example.com"); err != nil {
+		t.Fatalf("Suppress failed: %v", err)
+	}
+
+	application, err = initDryRunServices(cfg, t.TempDir())
+	if err != nil {
+		t.Fatalf("initDryRunServices failed: %v", err)
+	}
+	if application.Suppressions == nil {
+		t.Fatal("Expected the existing suppression list to be read")
+	}
+	if suppressed, _ := application.Suppressions.IsSuppressed(1, "reader@example.com"); !suppressed {
+		t.Error("Expected the dry run to see existing suppressions")
+	}
+	if _, err := os.Stat(CachePath); err == nil {
+		t.Error("Expected a dry run not to create the cache directory")
+	}
+}
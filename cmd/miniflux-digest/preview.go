@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/app"
+	"miniflux-digest/internal/config"
+	"miniflux-digest/internal/digest"
+	"miniflux-digest/internal/email"
+	"miniflux-digest/internal/models"
+	"miniflux-digest/internal/sample"
+	"miniflux-digest/internal/templates"
+)
+
+func openBrowser(url string) error {
+	var cmd string
+	var args []string
+
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = "open"
+	case "windows":
+		cmd = "cmd"
+		args = []string{"/c", "start"}
+	case "linux":
+		cmd = "xdg-open"
+	default:
+		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
+	}
+
+	args = append(args, url)
+	return exec.Command(cmd, args...).Start()
+}
+
+func generateDigestData(cfg *config.Config, categoryID int64) (*models.HTMLTemplateData, error) {
+	llmService, err := newLLMService(&cfg.AI, CachePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create LLM service: %w", err)
+	}
+
+	digestSvc, err := newDigestService(&cfg.AI, llmService, CachePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create digest service: %w", err)
+	}
+
+	if categoryID == 0 {
+		log.Println("Building digest data with sample data...")
+		icons, err := sample.FeedIcons()
+		if err != nil {
+			return nil, err
+		}
+		return digestSvc.BuildDigestData(sample.Category(), sample.Entries(), icons, cfg.Digest.GroupBy, cfg.Miniflux.Host), nil
+	}
+
+	log.Printf("Fetching Miniflux data for category %d...", categoryID)
+	minifluxClient := miniflux.NewClient(cfg.Miniflux.Host, cfg.Miniflux.ApiToken)
+	clientWrapper := app.NewMinifluxClientWrapper(minifluxClient, app.WithMaxEntries(cfg.Digest.MaxEntries))
+
+	rawData, err := clientWrapper.FetchRawCategoryData(categoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch category data: %w", err)
+	}
+
+	digestConfig := cfg.Digest.ForCategory(rawData.Category.ID, rawData.Category.Title)
+	data := digestSvc.BuildDigestData(rawData.Category, rawData.Entries, rawData.Icons, digestConfig.GroupBy, cfg.Miniflux.Host)
+	data.RemainingEntries = max(rawData.Total-len(*rawData.Entries), 0)
+	return data, nil
+}
+
+func writePreviewHTML(data *models.HTMLTemplateData, compress bool) (string, error) {
+	var buf bytes.Buffer
+	if err := templates.ArchiveTemplate.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	html, err := digest.MinifyHTML(buf.Bytes(), compress)
+	if err != nil {
+		return "", fmt.Errorf("failed to minify HTML: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "miniflux-digest-preview")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	filePath := filepath.Join(tmpDir, "preview.html")
+
+	if err := os.WriteFile(filePath, html, 0644); err != nil {
+		return "", fmt.Errorf("failed to write HTML to file: %w", err)
+	}
+	return filePath, nil
+}
+
+func sendPreviewEmail(cfg *config.Config, filePath string, data *models.HTMLTemplateData) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open HTML file for email: %w", err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Error closing file: %v", err)
+		}
+	}()
+
+	emailSvc, err := email.NewEmailService(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create email service: %w", err)
+	}
+	defer func() {
+		if err := emailSvc.Close(); err != nil {
+			log.Printf("Error closing email service: %v", err)
+		}
+	}()
+
+	if err := emailSvc.Send(cfg.ForCategory(data.Category.ID, data.Category.Title), file, data); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func previewCommand(args []string, stdout io.Writer) error {
+	fs, configPath := newFlagSet("preview", stdout)
+	sendEmail := fs.Bool("email", false, "send the generated HTML as an email")
+	categoryID := fs.Int64("category", 0, "Miniflux category ID to fetch entries from, sample data is used when unset")
+	noBrowser := fs.Bool("no-browser", false, "do not open the generated HTML in a browser")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	data, err := generateDigestData(cfg, *categoryID)
+	if err != nil {
+		return err
+	}
+
+	filePath, err := writePreviewHTML(data, cfg.Digest.Compress)
+	if err != nil {
+		return err
+	}
+
+	if *sendEmail {
+		if err := sendPreviewEmail(cfg, filePath, data); err != nil {
+			return err
+		}
+		log.Printf("Sent preview email for %s", filePath)
+	}
+
+	if _, err := fmt.Fprintf(stdout, "Preview available at: file://%s\n", filePath); err != nil {
+		return err
+	}
+
+	if !*noBrowser {
+		if err := openBrowser(fmt.Sprintf("file://%s", filePath)); err != nil {
+			log.Printf("Failed to open browser: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -12,12 +12,14 @@ type App struct {
 	MinifluxClientService MinifluxClientService
 	DigestService         DigestService
 	LLMService            llm.LLMService
+	Outbox                Outbox
+	Suppressions          SuppressionList
 }
 
 type Option func(*App)
 
 func NewApp(opts ...Option) *App {
-	app := &App{}
+	app := &App{Outbox: NewMemoryOutbox()}
 	for _, opt := range opts {
 		opt(app)
 	}
@@ -59,3 +61,15 @@ func WithLLMService(s llm.LLMService) Option {
 		a.LLMService = s
 	}
 }
+
+func WithOutbox(o Outbox) Option {
+	return func(a *App) {
+		a.Outbox = o
+	}
+}
+
+func WithSuppressionList(l SuppressionList) Option {
+	return func(a *App) {
+		a.Suppressions = l
+	}
+}
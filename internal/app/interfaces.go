@@ -16,6 +16,19 @@ type ArchiveService interface {
 
 type EmailService interface {
 		Send(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error
+		Close() error
+}
+
+type Outbox interface {
+	Save(delivery *PendingDelivery) error
+	Remove(id string) error
+	List() ([]*PendingDelivery, error)
+}
+
+// SuppressionList records recipients that unsubscribed from a category.
+type SuppressionList interface {
+	Suppress(categoryID int64, email string) error
+	IsSuppressed(categoryID int64, email string) (bool, error)
 }
 
 type DigestService interface {
@@ -24,8 +37,11 @@ type DigestService interface {
 
 type MinifluxClientService interface {
 	MarkCategoryAsRead(categoryID int64) error
+	MarkEntriesAsRead(entryIDs []int64) error
 	CategoryEntries(categoryID int64, filter *miniflux.Filter) (*miniflux.Entries, error)
 	CategoryFeeds(categoryID int64) ([]*miniflux.Feed, error)
 	FeedIcon(feedID int64) (*miniflux.FeedIcon, error)
+	Categories() ([]*miniflux.Category, error)
+	FetchRawCategoryData(categoryID int64) (*RawCategoryData, error)
 	StreamAllCategoryData() <-chan *RawCategoryData
 }
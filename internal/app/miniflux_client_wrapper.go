@@ -8,22 +8,79 @@ import (
 	miniflux "miniflux.app/v2/client"
 )
 
+const (
+	MarkAsReadBatchSize = 100
+	EntriesPageSize     = 100
+)
+
 type MinifluxClientWrapper struct {
-	client *miniflux.Client
+	client         *miniflux.Client
+	maxEntries     int
+	categoryFilter func(*miniflux.Category) bool
 }
 
-func NewMinifluxClientWrapper(client *miniflux.Client) *MinifluxClientWrapper {
-	return &MinifluxClientWrapper{client: client}
+type MinifluxClientOption func(*MinifluxClientWrapper)
+
+func NewMinifluxClientWrapper(client *miniflux.Client, opts ...MinifluxClientOption) *MinifluxClientWrapper {
+	wrapper := &MinifluxClientWrapper{client: client}
+	for _, opt := range opts {
+		opt(wrapper)
+	}
+	return wrapper
+}
+
+func WithMaxEntries(maxEntries int) MinifluxClientOption {
+	return func(m *MinifluxClientWrapper) {
+		m.maxEntries = maxEntries
+	}
+}
+
+func WithCategoryFilter(filter func(*miniflux.Category) bool) MinifluxClientOption {
+	return func(m *MinifluxClientWrapper) {
+		m.categoryFilter = filter
+	}
 }
 
 func (m *MinifluxClientWrapper) MarkCategoryAsRead(categoryID int64) error {
 	return m.client.MarkCategoryAsRead(categoryID)
 }
 
+func (m *MinifluxClientWrapper) MarkEntriesAsRead(entryIDs []int64) error {
+	for start := 0; start < len(entryIDs); start += MarkAsReadBatchSize {
+		end := min(start+MarkAsReadBatchSize, len(entryIDs))
+
+		if err := m.client.UpdateEntries(entryIDs[start:end], miniflux.EntryStatusRead); err != nil {
+			return fmt.Errorf("failed to mark entries %d-%d of %d as read: %w", start+1, end, len(entryIDs), err)
+		}
+	}
+
+	return nil
+}
+
 func (m *MinifluxClientWrapper) categories() ([]*miniflux.Category, error) {
 	return m.client.Categories()
 }
 
+// Categories returns the categories that pass the configured category filter.
+func (m *MinifluxClientWrapper) Categories() ([]*miniflux.Category, error) {
+	categories, err := m.categories()
+	if err != nil {
+		return nil, err
+	}
+
+	if m.categoryFilter == nil {
+		return categories, nil
+	}
+
+	filtered := make([]*miniflux.Category, 0, len(categories))
+	for _, category := range categories {
+		if m.categoryFilter(category) {
+			filtered = append(filtered, category)
+		}
+	}
+	return filtered, nil
+}
+
 func (m *MinifluxClientWrapper) CategoryEntries(categoryID int64, filter *miniflux.Filter) (*miniflux.Entries, error) {
 	entries, err := m.client.CategoryEntries(categoryID, filter)
 	if err != nil {
@@ -43,10 +100,47 @@ func (m *MinifluxClientWrapper) FeedIcon(feedID int64) (*miniflux.FeedIcon, erro
 type RawCategoryData struct {
 	Category *miniflux.Category
 	Entries  *miniflux.Entries
+	Total    int
 	Feeds    []*miniflux.Feed
 	Icons    map[int64]*models.FeedIcon
 }
 
+func (m *MinifluxClientWrapper) fetchUnreadEntries(categoryID int64) (miniflux.Entries, int, error) {
+	entries := miniflux.Entries{}
+	total := 0
+
+	for {
+		limit := EntriesPageSize
+		if m.maxEntries > 0 {
+			limit = min(limit, m.maxEntries-len(entries))
+		}
+
+		result, err := m.client.CategoryEntries(categoryID, &miniflux.Filter{
+			Status:    miniflux.EntryStatusUnread,
+			Order:     "id",
+			Direction: "asc",
+			Limit:     limit,
+			Offset:    len(entries),
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+
+		total = result.Total
+		entries = append(entries, result.Entries...)
+
+		if len(result.Entries) == 0 || len(entries) >= total {
+			break
+		}
+
+		if m.maxEntries > 0 && len(entries) >= m.maxEntries {
+			break
+		}
+	}
+
+	return entries, max(total, len(entries)), nil
+}
+
 func (m *MinifluxClientWrapper) FetchRawCategoryData(categoryID int64) (*RawCategoryData, error) {
 	categories, err := m.categories()
 	if err != nil {
@@ -65,7 +159,7 @@ func (m *MinifluxClientWrapper) FetchRawCategoryData(categoryID int64) (*RawCate
 		return nil, fmt.Errorf("category with ID %d not found", categoryID)
 	}
 
-	entriesResult, err := m.client.CategoryEntries(category.ID, &miniflux.Filter{Status: miniflux.EntryStatusUnread})
+	entries, total, err := m.fetchUnreadEntries(category.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -92,7 +186,8 @@ func (m *MinifluxClientWrapper) FetchRawCategoryData(categoryID int64) (*RawCate
 
 	return &RawCategoryData{
 		Category: category,
-		Entries:  &entriesResult.Entries,
+		Entries:  &entries,
+		Total:    total,
 		Feeds:    feeds,
 		Icons:    feedIcons,
 	}, nil
@@ -104,7 +199,7 @@ func (m *MinifluxClientWrapper) StreamAllCategoryData() <-chan *RawCategoryData
 	go func() {
 		defer close(out)
 
-		categories, err := m.categories()
+		categories, err := m.Categories()
 
 		if err != nil {
 			log.Printf("Streamer failed to fetch categories: %v", err)
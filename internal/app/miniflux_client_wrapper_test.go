@@ -1,9 +1,11 @@
 package app_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"miniflux-digest/internal/app"
@@ -61,6 +63,109 @@ func TestMinifluxClientWrapper_StreamAllCategoryData(t *testing.T) {
 	}
 }
 
+func TestMinifluxClientWrapper_StreamAllCategoryData_CategoryFilter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/categories":
+			if _, err := fmt.Fprintln(w, `[{"id": 1, "title": "News"}, {"id": 2, "title": "Podcasts"}]`); err != nil {
+				panic(err)
+			}
+		case "/v1/categories/1/entries":
+			if _, err := fmt.Fprintln(w, `{"total": 1, "entries": [{"id": 101, "title": "Entry 1A"}]}`); err != nil {
+				panic(err)
+			}
+		case "/v1/categories/1/feeds":
+			if _, err := fmt.Fprintln(w, `[]`); err != nil {
+				panic(err)
+			}
+		default:
+			t.Errorf("Unexpected request path for excluded category: %s", r.URL.Path)
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := miniflux.NewClient(server.URL, "test-token")
+	wrapper := app.NewMinifluxClientWrapper(client, app.WithCategoryFilter(func(category *miniflux.Category) bool {
+		return category.Title != "Podcasts"
+	}))
+
+	var titles []string
+	for data := range wrapper.StreamAllCategoryData() {
+		titles = append(titles, data.Category.Title)
+	}
+
+	if len(titles) != 1 || titles[0] != "News" {
+		t.Errorf("Expected only the News category, got %v", titles)
+	}
+}
+
+func TestMinifluxClientWrapper_FetchRawCategoryData_Pagination(t *testing.T) {
+	const totalEntries = 250
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/categories":
+			if _, err := fmt.Fprintln(w, `[{"id": 1, "title": "Test Category 1"}]`); err != nil {
+				panic(err)
+			}
+		case "/v1/categories/1/entries":
+			offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			if limit <= 0 || limit > app.EntriesPageSize {
+				t.Errorf("Unexpected page limit %d", limit)
+			}
+
+			entries := []map[string]any{}
+			for id := offset + 1; id <= min(offset+limit, totalEntries); id++ {
+				entries = append(entries, map[string]any{"id": id, "title": fmt.Sprintf("Entry %d", id)})
+			}
+			if err := json.NewEncoder(w).Encode(map[string]any{"total": totalEntries, "entries": entries}); err != nil {
+				panic(err)
+			}
+		case "/v1/categories/1/feeds":
+			if _, err := fmt.Fprintln(w, `[]`); err != nil {
+				panic(err)
+			}
+		default:
+			t.Fatalf("Unexpected request path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := miniflux.NewClient(server.URL, "test-token")
+
+	tests := []struct {
+		name        string
+		maxEntries  int
+		wantEntries int
+	}{
+		{name: "no limit", maxEntries: 0, wantEntries: totalEntries},
+		{name: "hard cap", maxEntries: 150, wantEntries: 150},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wrapper := app.NewMinifluxClientWrapper(client, app.WithMaxEntries(tt.maxEntries))
+
+			data, err := wrapper.FetchRawCategoryData(1)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if len(*data.Entries) != tt.wantEntries {
+				t.Errorf("Expected %d entries, got %d", tt.wantEntries, len(*data.Entries))
+			}
+			if data.Total != totalEntries {
+				t.Errorf("Expected total to be %d, got %d", totalEntries, data.Total)
+			}
+			if last := (*data.Entries)[len(*data.Entries)-1]; last.ID != int64(tt.wantEntries) {
+				t.Errorf("Expected last entry ID to be %d, got %d", tt.wantEntries, last.ID)
+			}
+		})
+	}
+}
+
 func TestMinifluxClientWrapper_MarkCategoryAsRead(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPut {
@@ -78,6 +183,52 @@ func TestMinifluxClientWrapper_MarkCategoryAsRead(t *testing.T) {
 	}
 }
 
+func TestMinifluxClientWrapper_MarkEntriesAsRead(t *testing.T) {
+	var batches [][]int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.URL.Path != "/v1/entries" {
+			t.Errorf("Unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+
+		var body struct {
+			EntryIDs []int64 `json:"entry_ids"`
+			Status   string  `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode request body: %v", err)
+		}
+		if body.Status != miniflux.EntryStatusRead {
+			t.Errorf("Expected status %q, got %q", miniflux.EntryStatusRead, body.Status)
+		}
+
+		batches = append(batches, body.EntryIDs)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := miniflux.NewClient(server.URL, "test-token")
+	wrapper := app.NewMinifluxClientWrapper(client)
+
+	entryIDs := make([]int64, app.MarkAsReadBatchSize+1)
+	for i := range entryIDs {
+		entryIDs[i] = int64(i + 1)
+	}
+
+	if err := wrapper.MarkEntriesAsRead(entryIDs); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	if len(batches) != 2 {
+		t.Fatalf("Expected 2 batches, got %d", len(batches))
+	}
+	if len(batches[0]) != app.MarkAsReadBatchSize || len(batches[1]) != 1 {
+		t.Errorf("Unexpected batch sizes: %d and %d", len(batches[0]), len(batches[1]))
+	}
+	if batches[1][0] != int64(app.MarkAsReadBatchSize+1) {
+		t.Errorf("Expected last batch to contain entry %d, got %d", app.MarkAsReadBatchSize+1, batches[1][0])
+	}
+}
+
 func TestMinifluxClientWrapper_CategoryEntries(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if _, err := fmt.Fprintln(w, `{"total": 1, "entries": [{"id": 101, "title": "Entry 1A"}]}`); err != nil {
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"miniflux-digest/internal/models"
+)
+
+// PendingDelivery is a digest email that failed to send and is waiting in the
+// outbox to be retried. The configuration is resolved again from the category
+// on every attempt, so no credentials are stored with it. Recipients holds
+// the recipients still waiting for an individually sent digest, it is empty
+// when the digest is retried for every recipient.
+type PendingDelivery struct {
+	ID          string                   `json:"id"`
+	Data        *models.HTMLTemplateData `json:"data"`
+	FilePath    string                   `json:"file_path"`
+	Recipients  []string                 `json:"recipients,omitempty"`
+	Attempts    int                      `json:"attempts"`
+	CreatedAt   time.Time                `json:"created_at"`
+	NextAttempt time.Time                `json:"next_attempt"`
+	LastError   string                   `json:"last_error"`
+}
+
+// UndeliveredError is returned by an EmailService when only some of the
+// individual copies of a digest were sent, so a retry can skip the
+// recipients that already received it.
+type UndeliveredError struct {
+	Recipients []string
+	Err        error
+}
+
+func (e *UndeliveredError) Error() string {
+	return fmt.Sprintf("digest not sent to %s: %v", strings.Join(e.Recipients, ", "), e.Err)
+}
+
+func (e *UndeliveredError) Unwrap() error {
+	return e.Err
+}
+
+// MemoryOutbox keeps pending deliveries in memory, for runs that do not need
+// them to survive a restart.
+type MemoryOutbox struct {
+	mu      sync.Mutex
+	pending []*PendingDelivery
+}
+
+var _ Outbox = (*MemoryOutbox)(nil)
+
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{}
+}
+
+func (o *MemoryOutbox) Save(delivery *PendingDelivery) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	i := slices.IndexFunc(o.pending, func(d *PendingDelivery) bool { return d.ID == delivery.ID })
+	if i >= 0 {
+		o.pending[i] = delivery
+	} else {
+		o.pending = append(o.pending, delivery)
+	}
+	return nil
+}
+
+func (o *MemoryOutbox) Remove(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.pending = slices.DeleteFunc(o.pending, func(d *PendingDelivery) bool { return d.ID == id })
+	return nil
+}
+
+func (o *MemoryOutbox) List() ([]*PendingDelivery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return slices.Clone(o.pending), nil
+}
@@ -3,11 +3,20 @@ package config
 import (
 	"errors"
 	"fmt"
+	"log"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/confmap"
-	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/providers/env/v2"
 	"github.com/knadh/koanf/v2"
 	"github.com/robfig/cron/v3"
 
@@ -26,42 +35,372 @@ func IsValidGocronSchedule(s string) bool {
 	return err == nil
 }
 
+type MarkAsReadPolicy string
+
+const (
+	MarkAsReadOnSuccess MarkAsReadPolicy = "on_success"
+	MarkAsReadAlways    MarkAsReadPolicy = "always"
+	MarkAsReadNever     MarkAsReadPolicy = "never"
+)
+
+type EmailFormat string
+
+const (
+	EmailFormatAttachment EmailFormat = "attachment"
+	EmailFormatInline     EmailFormat = "inline"
+	EmailFormatBoth       EmailFormat = "both"
+)
+
+type SmtpTLS string
+
+const (
+	SmtpTLSNone              SmtpTLS = "none"
+	SmtpTLSStartTLS          SmtpTLS = "starttls"
+	SmtpTLSStartTLSMandatory SmtpTLS = "starttls_mandatory"
+	SmtpTLSImplicit          SmtpTLS = "implicit"
+)
+
+type SmtpAuth string
+
+const (
+	SmtpAuthNone    SmtpAuth = "none"
+	SmtpAuthPlain   SmtpAuth = "plain"
+	SmtpAuthLogin   SmtpAuth = "login"
+	SmtpAuthCramMD5 SmtpAuth = "crammd5"
+	SmtpAuthAuto    SmtpAuth = "auto"
+)
+
+type EmailTransport string
+
+const (
+	EmailTransportSMTP     EmailTransport = "smtp"
+	EmailTransportSendmail EmailTransport = "sendmail"
+	EmailTransportMaildir  EmailTransport = "maildir"
+)
+
+const EnvPrefix = "MINIFLUX_DIGEST_"
+
 type ConfigMiniflux struct {
-	Host     string `koanf:"host" validate:"required"`
-	ApiToken string `koanf:"api_token" validate:"required"`
+	Host         string `koanf:"host" validate:"required"`
+	ApiToken     string `koanf:"api_token" validate:"required"`
+	ApiTokenFile string `koanf:"api_token_file"`
 }
 
 type ConfigDigestEmail struct {
-	To			string `koanf:"to" validate:"omitempty,email"`
-	From		string `koanf:"from" validate:"omitempty,email"`
+	To               []string    `koanf:"to" validate:"dive,email"`
+	Cc               []string    `koanf:"cc" validate:"dive,email"`
+	Bcc              []string    `koanf:"bcc" validate:"dive,email"`
+	From             string      `koanf:"from" validate:"omitempty,email"`
+	Format           EmailFormat `koanf:"format" validate:"omitempty,oneof=attachment inline both"`
+	Subject          string      `koanf:"subject" validate:"omitempty,subject"`
+	BodyTemplateFile string      `koanf:"body_template_file" validate:"omitempty,file"`
+	Individual       bool        `koanf:"individual"`
+}
+
+// Recipients returns every to, cc and bcc address once, in that order.
+func (e *ConfigDigestEmail) Recipients() []string {
+	var recipients []string
+	for _, address := range slices.Concat(e.To, e.Cc, e.Bcc) {
+		if !slices.Contains(recipients, address) {
+			recipients = append(recipients, address)
+		}
+	}
+	return recipients
+}
+
+type ConfigCategoryEmail struct {
+	To               []string    `koanf:"to" validate:"dive,email"`
+	Cc               []string    `koanf:"cc" validate:"dive,email"`
+	Bcc              []string    `koanf:"bcc" validate:"dive,email"`
+	From             string      `koanf:"from" validate:"omitempty,email"`
+	Format           EmailFormat `koanf:"format" validate:"omitempty,oneof=attachment inline both"`
+	Subject          string      `koanf:"subject" validate:"omitempty,subject"`
+	BodyTemplateFile string      `koanf:"body_template_file" validate:"omitempty,file"`
+	Individual       *bool       `koanf:"individual"`
 }
 
 type ConfigSmtp struct {
-	Host     string `koanf:"host"`
-	Port     int    `koanf:"port" validate:"omitempty,min=1,max=65535"`
-	User     string `koanf:"user"`
-	Password string `koanf:"password"`
+	Host               string     `koanf:"host"`
+	Port               int        `koanf:"port" validate:"omitempty,min=1,max=65535"`
+	User               string     `koanf:"user"`
+	Password           string     `koanf:"password"`
+	PasswordFile       string     `koanf:"password_file"`
+	TLS                SmtpTLS    `koanf:"tls" validate:"omitempty,oneof=none starttls starttls_mandatory implicit"`
+	Auth               SmtpAuth   `koanf:"auth" validate:"omitempty,oneof=none plain login crammd5 auto"`
+	InsecureSkipVerify bool       `koanf:"insecure_skip_verify"`
+	CAFile             string     `koanf:"ca_file" validate:"omitempty,file"`
+	MaxSendRate        int        `koanf:"max_send_rate" validate:"min=0"`
+	DKIM               ConfigDKIM `koanf:"dkim"`
+}
+
+// ConfigDKIM enables DKIM signing of outgoing email when all fields are set.
+type ConfigDKIM struct {
+	Selector       string `koanf:"selector" validate:"required_with=Domain PrivateKeyFile"`
+	Domain         string `koanf:"domain" validate:"required_with=Selector PrivateKeyFile,omitempty,fqdn"`
+	PrivateKeyFile string `koanf:"private_key_file" validate:"required_with=Selector Domain,omitempty,file"`
+}
+
+// Enabled reports whether DKIM signing is configured.
+func (d ConfigDKIM) Enabled() bool {
+	return d.Selector != "" && d.Domain != "" && d.PrivateKeyFile != ""
+}
+
+type ConfigCategory struct {
+	Email            ConfigCategoryEmail `koanf:"email"`
+	Schedule         string              `koanf:"schedule" validate:"omitempty,gocron"`
+	GroupBy          digest.GroupingType `koanf:"group_by" validate:"omitempty,oneof=day feed ai"`
+	MarkAsReadPolicy MarkAsReadPolicy    `koanf:"mark_as_read_policy" validate:"omitempty,oneof=on_success always never"`
 }
 
 type ConfigDigest struct {
-	Email        ConfigDigestEmail         `koanf:"email"`
-	Schedule     string                    `koanf:"schedule" validate:"gocron"`
-	Host         string                    `koanf:"host"`
-	Compress     bool                      `koanf:"compress"`
-	GroupBy      digest.GroupingType       `koanf:"group_by" validate:"omitempty,oneof=day feed ai"`
-	MarkAsRead   bool                      `koanf:"mark_as_read"`
-	RunOnStartup bool                      `koanf:"run_on_startup"`
+	Email             ConfigDigestEmail         `koanf:"email"`
+	Schedule          string                    `koanf:"schedule" validate:"gocron"`
+	Host              string                    `koanf:"host"`
+	Compress          bool                      `koanf:"compress"`
+	GroupBy           digest.GroupingType       `koanf:"group_by" validate:"omitempty,oneof=day feed ai"`
+	MarkAsReadPolicy  MarkAsReadPolicy          `koanf:"mark_as_read_policy" validate:"omitempty,oneof=on_success always never"`
+	RunOnStartup      bool                      `koanf:"run_on_startup"`
+	MaxEntries        int                       `koanf:"max_entries" validate:"min=0"`
+	Categories        map[string]ConfigCategory `koanf:"categories" validate:"dive"`
+	IncludeCategories []string                  `koanf:"include_categories" validate:"dive,glob"`
+	ExcludeCategories []string                  `koanf:"exclude_categories" validate:"dive,glob"`
+	Unsubscribe       ConfigUnsubscribe         `koanf:"unsubscribe"`
+}
+
+// ConfigUnsubscribe enables List-Unsubscribe links when a secret is set. The
+// secret signs the links, which are served under digest.host.
+type ConfigUnsubscribe struct {
+	Secret     string `koanf:"secret"`
+	SecretFile string `koanf:"secret_file"`
 }
 
+// matchesCategory reports whether pattern matches a category by ID, exact
+// title or glob pattern on the title.
+func matchesCategory(pattern string, id int64, title string) bool {
+	if pattern == strconv.FormatInt(id, 10) || pattern == title {
+		return true
+	}
+	matched, err := path.Match(pattern, title)
+	return err == nil && matched
+}
+
+// IncludesCategory reports whether a category passes the include_categories
+// and exclude_categories filters.
+func (d *ConfigDigest) IncludesCategory(id int64, title string) bool {
+	if len(d.IncludeCategories) > 0 && !slices.ContainsFunc(d.IncludeCategories, func(pattern string) bool {
+		return matchesCategory(pattern, id, title)
+	}) {
+		return false
+	}
+
+	return !slices.ContainsFunc(d.ExcludeCategories, func(pattern string) bool {
+		return matchesCategory(pattern, id, title)
+	})
+}
+
+// mergeCategories fills every unset field of a category override with the
+// global digest value, so each override describes a complete configuration.
+func (d *ConfigDigest) mergeCategories() {
+	for key, category := range d.Categories {
+		if len(category.Email.To) == 0 {
+			category.Email.To = d.Email.To
+		}
+		if len(category.Email.Cc) == 0 {
+			category.Email.Cc = d.Email.Cc
+		}
+		if len(category.Email.Bcc) == 0 {
+			category.Email.Bcc = d.Email.Bcc
+		}
+		if category.Email.From == "" {
+			category.Email.From = d.Email.From
+		}
+		if category.Email.Format == "" {
+			category.Email.Format = d.Email.Format
+		}
+		if category.Email.Subject == "" {
+			category.Email.Subject = d.Email.Subject
+		}
+		if category.Email.BodyTemplateFile == "" {
+			category.Email.BodyTemplateFile = d.Email.BodyTemplateFile
+		}
+		if category.Email.Individual == nil {
+			individual := d.Email.Individual
+			category.Email.Individual = &individual
+		}
+		if category.Schedule == "" {
+			category.Schedule = d.Schedule
+		}
+		if category.GroupBy == "" {
+			category.GroupBy = d.GroupBy
+		}
+		if category.MarkAsReadPolicy == "" {
+			category.MarkAsReadPolicy = d.MarkAsReadPolicy
+		}
+		d.Categories[key] = category
+	}
+}
+
+func (d *ConfigDigest) findCategory(id int64, title string) (ConfigCategory, bool) {
+	if category, ok := d.Categories[strconv.FormatInt(id, 10)]; ok {
+		return category, true
+	}
+	if category, ok := d.Categories[title]; ok {
+		return category, true
+	}
+	for key, category := range d.Categories {
+		if strings.EqualFold(key, title) {
+			return category, true
+		}
+	}
+	return ConfigCategory{}, false
+}
+
+// ForCategory returns the digest configuration for a category, applying any
+// override configured under digest.categories by category ID or title.
+func (d ConfigDigest) ForCategory(id int64, title string) ConfigDigest {
+	category, ok := d.findCategory(id, title)
+	if !ok {
+		return d
+	}
+
+	if len(category.Email.To) > 0 {
+		d.Email.To = category.Email.To
+	}
+	if len(category.Email.Cc) > 0 {
+		d.Email.Cc = category.Email.Cc
+	}
+	if len(category.Email.Bcc) > 0 {
+		d.Email.Bcc = category.Email.Bcc
+	}
+	if category.Email.From != "" {
+		d.Email.From = category.Email.From
+	}
+	if category.Email.Format != "" {
+		d.Email.Format = category.Email.Format
+	}
+	if category.Email.Subject != "" {
+		d.Email.Subject = category.Email.Subject
+	}
+	if category.Email.BodyTemplateFile != "" {
+		d.Email.BodyTemplateFile = category.Email.BodyTemplateFile
+	}
+	if category.Email.Individual != nil {
+		d.Email.Individual = *category.Email.Individual
+	}
+	if category.Schedule != "" {
+		d.Schedule = category.Schedule
+	}
+	if category.GroupBy != "" {
+		d.GroupBy = category.GroupBy
+	}
+	if category.MarkAsReadPolicy != "" {
+		d.MarkAsReadPolicy = category.MarkAsReadPolicy
+	}
+
+	return d
+}
+
+// ForCategory returns a copy of the configuration with the digest settings
+// resolved for the given category.
+func (c *Config) ForCategory(id int64, title string) *Config {
+	cfg := *c
+	cfg.Digest = c.Digest.ForCategory(id, title)
+	return &cfg
+}
+
+// BodyTemplateFiles returns every digest.email.body_template_file, including
+// the category overrides, so the templates can be parsed once at startup.
+func (c *Config) BodyTemplateFiles() []string {
+	var files []string
+	if c.Digest.Email.BodyTemplateFile != "" {
+		files = append(files, c.Digest.Email.BodyTemplateFile)
+	}
+	for _, category := range c.Digest.Categories {
+		if category.Email.BodyTemplateFile != "" && !slices.Contains(files, category.Email.BodyTemplateFile) {
+			files = append(files, category.Email.BodyTemplateFile)
+		}
+	}
+	return files
+}
+
+// Subjects returns every configured digest.email.subject template, globally
+// and per category.
+func (c *Config) Subjects() []string {
+	var subjects []string
+	if c.Digest.Email.Subject != "" {
+		subjects = append(subjects, c.Digest.Email.Subject)
+	}
+	for _, category := range c.Digest.Categories {
+		if category.Email.Subject != "" && !slices.Contains(subjects, category.Email.Subject) {
+			subjects = append(subjects, category.Email.Subject)
+		}
+	}
+	return subjects
+}
+
+type AIProvider string
+
+const (
+	AIProviderGemini AIProvider = "gemini"
+	AIProviderOpenAI AIProvider = "openai"
+)
+
 type ConfigAI struct {
-	ApiKey string `koanf:"api_key"`
+	Provider        AIProvider    `koanf:"provider" validate:"omitempty,oneof=gemini openai"`
+	BaseURL         string        `koanf:"base_url" validate:"omitempty,url"`
+	Model           string        `koanf:"model"`
+	ApiKey          string        `koanf:"api_key"`
+	ApiKeyFile      string        `koanf:"api_key_file"`
+	Temperature     *float64      `koanf:"temperature" validate:"omitempty,min=0,max=2"`
+	MaxOutputTokens int           `koanf:"max_output_tokens" validate:"min=0"`
+	Timeout         time.Duration `koanf:"timeout" validate:"min=0"`
+	MaxInputTokens  int           `koanf:"max_input_tokens" validate:"min=0"`
+	EntrySummaries  bool          `koanf:"entry_summaries"`
+	Cache           ConfigAICache `koanf:"cache"`
+}
+
+type ConfigAICache struct {
+	TTL       time.Duration `koanf:"ttl" validate:"min=0"`
+	MaxSizeMB int           `koanf:"max_size_mb" validate:"min=0"`
+}
+
+// RequiresApiKey reports whether the provider cannot be used without an API
+// key. OpenAI compatible servers running locally usually do not need one.
+func (a ConfigAI) RequiresApiKey() bool {
+	return a.Provider != AIProviderOpenAI
+}
+
+type ConfigSendmail struct {
+	Path string   `koanf:"path"`
+	Args []string `koanf:"args"`
+}
+
+type ConfigMaildir struct {
+	Path string `koanf:"path"`
+}
+
+type ConfigEmail struct {
+	Transport EmailTransport `koanf:"transport" validate:"omitempty,oneof=smtp sendmail maildir"`
+	Sendmail  ConfigSendmail `koanf:"sendmail"`
+	Maildir   ConfigMaildir  `koanf:"maildir"`
+}
+
+type ConfigTemplates struct {
+	Dir string `koanf:"dir" validate:"omitempty,dir"`
+}
+
+type ConfigOutbox struct {
+	MaxAge time.Duration `koanf:"max_age" validate:"min=0"`
 }
 
 type Config struct {
-	Miniflux ConfigMiniflux `koanf:"miniflux"`
-	Smtp     ConfigSmtp     `koanf:"smtp"`
-	Digest   ConfigDigest   `koanf:"digest"`
-	AI       ConfigAI       `koanf:"ai"`
+	Miniflux  ConfigMiniflux  `koanf:"miniflux"`
+	Smtp      ConfigSmtp      `koanf:"smtp"`
+	Email     ConfigEmail     `koanf:"email"`
+	Digest    ConfigDigest    `koanf:"digest"`
+	AI        ConfigAI        `koanf:"ai"`
+	Outbox    ConfigOutbox    `koanf:"outbox"`
+	Templates ConfigTemplates `koanf:"templates"`
 }
 
 func (c *Config) Validate() error {
@@ -72,11 +411,57 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("failed to register gocron validator: %w", err)
 	}
 
+	if err := validate.RegisterValidation("glob", func(fl validator.FieldLevel) bool {
+		_, err := path.Match(fl.Field().String(), "")
+		return err == nil
+	}); err != nil {
+		return fmt.Errorf("failed to register glob validator: %w", err)
+	}
+
+	if err := validate.RegisterValidation("subject", func(fl validator.FieldLevel) bool {
+		_, err := template.New("subject").Parse(fl.Field().String())
+		return err == nil
+	}); err != nil {
+		return fmt.Errorf("failed to register subject validator: %w", err)
+	}
+
 	validate.RegisterStructValidation(func(sl validator.StructLevel) {
 		cfg := sl.Current().Interface().(Config)
-		if cfg.Digest.GroupBy == "ai" && cfg.AI.ApiKey == "" {
+		if cfg.Digest.GroupBy == "ai" && cfg.AI.RequiresApiKey() && cfg.AI.ApiKey == "" {
 			sl.ReportError(cfg.AI.ApiKey, "AI.ApiKey", "ApiKey", "required_if", "Digest.GroupBy is 'ai'")
 		}
+		if cfg.AI.EntrySummaries && cfg.AI.RequiresApiKey() && cfg.AI.ApiKey == "" {
+			sl.ReportError(cfg.AI.ApiKey, "AI.ApiKey", "ApiKey", "required_if", "AI.EntrySummaries is set")
+		}
+		if cfg.Smtp.Auth != "" && cfg.Smtp.Auth != SmtpAuthNone && cfg.Smtp.Auth != SmtpAuthAuto && cfg.Smtp.User == "" {
+			sl.ReportError(cfg.Smtp.User, "Smtp.User", "User", "required_if", fmt.Sprintf("Smtp.Auth is '%s'", cfg.Smtp.Auth))
+		}
+		if cfg.Email.Transport == EmailTransportSendmail && cfg.Email.Sendmail.Path == "" {
+			sl.ReportError(cfg.Email.Sendmail.Path, "Email.Sendmail.Path", "Path", "required_if", "Email.Transport is 'sendmail'")
+		}
+		if cfg.Email.Transport == EmailTransportMaildir && cfg.Email.Maildir.Path == "" {
+			sl.ReportError(cfg.Email.Maildir.Path, "Email.Maildir.Path", "Path", "required_if", "Email.Transport is 'maildir'")
+		}
+		if len(cfg.Digest.Email.Recipients()) == 0 {
+			sl.ReportError(cfg.Digest.Email.To, "Digest.Email.To", "To", "required_without_all", "Digest.Email.Cc and Digest.Email.Bcc are empty")
+		}
+		if cfg.Digest.Unsubscribe.Secret != "" && cfg.Digest.Host == "" {
+			sl.ReportError(cfg.Digest.Host, "Digest.Host", "Host", "required_with", "Digest.Unsubscribe.Secret is set")
+		}
+		if cfg.Digest.Unsubscribe.Secret != "" && !cfg.Digest.Email.Individual {
+			sl.ReportError(cfg.Digest.Email.Individual, "Digest.Email.Individual", "Individual", "required_with", "Digest.Unsubscribe.Secret is set")
+		}
+		if cfg.Smtp.TLS == SmtpTLSNone && (cfg.Smtp.InsecureSkipVerify || cfg.Smtp.CAFile != "") {
+			sl.ReportError(cfg.Smtp.TLS, "Smtp.TLS", "TLS", "excluded_if", "Smtp.InsecureSkipVerify or Smtp.CAFile is set")
+		}
+		for key, category := range cfg.Digest.Categories {
+			if category.GroupBy == "ai" && cfg.AI.RequiresApiKey() && cfg.AI.ApiKey == "" {
+				sl.ReportError(cfg.AI.ApiKey, "AI.ApiKey", "ApiKey", "required_if", fmt.Sprintf("Digest.Categories[%s].GroupBy is 'ai'", key))
+			}
+			if cfg.Digest.Unsubscribe.Secret != "" && category.Email.Individual != nil && !*category.Email.Individual {
+				sl.ReportError(category.Email.Individual, fmt.Sprintf("Digest.Categories[%s].Email.Individual", key), "Individual", "required_with", "Digest.Unsubscribe.Secret is set")
+			}
+		}
 	}, Config{})
 
 	err := validate.Struct(c)
@@ -100,14 +485,49 @@ func Load(path string) (*Config, error) {
 		return nil, err
 	}
 
-	if err := k.Load(file.Provider(path), parser); err != nil {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := parser.Unmarshal(content)
+	if err != nil {
+		return nil, err
+	}
+	fileCategories, err := cutCategories(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := k.Load(confmap.Provider(raw, ""), nil); err != nil {
+		return nil, err
+	}
+
+	if err := k.Load(env.Provider(".", env.Opt{Prefix: EnvPrefix, TransformFunc: envKey}), nil); err != nil {
 		return nil, err
 	}
 
+	policy := k.String("digest.mark_as_read_policy")
+	if err := migrateMarkAsRead(k, "digest.", "digest", ""); err != nil {
+		return nil, err
+	}
+
+	categories, err := loadCategories(k, fileCategories, policy)
+	if err != nil {
+		return nil, err
+	}
+	k.Delete("digest.categories")
+
 	cfg := &Config{}
-	if err := k.Unmarshal("", &cfg); err != nil {
+	if err := k.UnmarshalWithConf("", &cfg, koanf.UnmarshalConf{DecoderConfig: decoderConfig()}); err != nil {
 		return nil, err
 	}
+	cfg.Digest.Categories = categories
+
+	if err := cfg.readSecretFiles(); err != nil {
+		return nil, err
+	}
+
+	cfg.Digest.mergeCategories()
 
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -116,12 +536,170 @@ func Load(path string) (*Config, error) {
 	return cfg, nil
 }
 
+// cutCategories removes digest.categories from the parsed YAML file and
+// returns it. Overrides are keyed by category title, which may contain the
+// "." koanf splits keys on, so they are loaded separately by loadCategories.
+func cutCategories(raw map[string]any) (map[string]any, error) {
+	d, ok := raw["digest"].(map[string]any)
+	if !ok || d["categories"] == nil {
+		return nil, nil
+	}
+
+	categories, ok := d["categories"].(map[string]any)
+	if !ok {
+		return nil, errors.New("digest.categories must map category IDs or titles to overrides")
+	}
+	delete(d, "categories")
+	return categories, nil
+}
+
+// loadCategories loads every digest.categories override on its own and merges
+// the ones set through environment variables over it. Environment variables
+// are always lowercased, so their keys match YAML keys case-insensitively.
+// Keys that match several overrides and unknown settings are rejected instead
+// of being dropped.
+func loadCategories(k *koanf.Koanf, fileCategories map[string]any, policy string) (map[string]ConfigCategory, error) {
+	overrides := make(map[string]*koanf.Koanf)
+	keys := make(map[string]string)
+	for key, value := range fileCategories {
+		settings, ok := value.(map[string]any)
+		if value != nil && !ok {
+			return nil, fmt.Errorf("digest.categories.%s must be a map of settings", key)
+		}
+
+		folded := strings.ToLower(key)
+		if other, ok := keys[folded]; ok {
+			return nil, fmt.Errorf("digest.categories has overrides for both %q and %q, titles are matched case-insensitively", other, key)
+		}
+		keys[folded] = key
+
+		override := koanf.New(".")
+		if err := override.Load(confmap.Provider(settings, ""), nil); err != nil {
+			return nil, err
+		}
+		overrides[key] = override
+	}
+
+	for _, folded := range k.MapKeys("digest.categories") {
+		env := k.Cut("digest.categories." + folded)
+		key, ok := keys[folded]
+		if !ok {
+			keys[folded] = folded
+			overrides[folded] = env
+			continue
+		}
+		if err := overrides[key].Merge(env); err != nil {
+			return nil, err
+		}
+	}
+
+	categories := make(map[string]ConfigCategory, len(overrides))
+	for key, override := range overrides {
+		if err := migrateMarkAsRead(override, "", "digest.categories."+key, policy); err != nil {
+			return nil, err
+		}
+
+		conf := decoderConfig()
+		conf.ErrorUnused = true
+		var category ConfigCategory
+		if err := override.UnmarshalWithConf("", &category, koanf.UnmarshalConf{DecoderConfig: conf}); err != nil {
+			return nil, fmt.Errorf("invalid digest.categories.%s: %w", key, err)
+		}
+		categories[key] = category
+	}
+	return categories, nil
+}
+
+// migrateMarkAsRead maps the deprecated mark_as_read setting under prefix
+// onto mark_as_read_policy. False means never, and for a category setting it
+// to true without a policy of its own, policy is the one the global digest
+// had before.
+func migrateMarkAsRead(k *koanf.Koanf, prefix, name, policy string) error {
+	if !k.Exists(prefix + "mark_as_read") {
+		return nil
+	}
+	log.Printf("%s.mark_as_read is deprecated, use %s.mark_as_read_policy instead", name, name)
+
+	markAsRead := k.Bool(prefix + "mark_as_read")
+	k.Delete(prefix + "mark_as_read")
+	if !markAsRead {
+		return k.Set(prefix+"mark_as_read_policy", string(MarkAsReadNever))
+	}
+	if policy != "" && !k.Exists(prefix+"mark_as_read_policy") {
+		return k.Set(prefix+"mark_as_read_policy", policy)
+	}
+	return nil
+}
+
+// decoderConfig extends the koanf defaults so that a comma separated string,
+// as set through an environment variable, can be used for a list.
+func decoderConfig() *mapstructure.DecoderConfig {
+	return &mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.TextUnmarshallerHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+		WeaklyTypedInput: true,
+	}
+}
+
+// envKey maps MINIFLUX_DIGEST_SMTP__PASSWORD to smtp.password, using a double
+// underscore to separate sections so single underscores can remain in keys.
+func envKey(key, value string) (string, any) {
+	key = strings.TrimPrefix(key, EnvPrefix)
+	key = strings.ReplaceAll(strings.ToLower(key), "__", ".")
+	return key, value
+}
+
+func readSecretFile(value *string, path string, name string) error {
+	if path == "" {
+		return nil
+	}
+
+	if *value != "" {
+		return fmt.Errorf("only one of %s and %s_file can be set", name, name)
+	}
+
+	secret, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s_file: %w", name, err)
+	}
+
+	*value = strings.TrimSpace(string(secret))
+	return nil
+}
+
+func (c *Config) readSecretFiles() error {
+	if err := readSecretFile(&c.Miniflux.ApiToken, c.Miniflux.ApiTokenFile, "miniflux.api_token"); err != nil {
+		return err
+	}
+	if err := readSecretFile(&c.Smtp.Password, c.Smtp.PasswordFile, "smtp.password"); err != nil {
+		return err
+	}
+	if err := readSecretFile(&c.Digest.Unsubscribe.Secret, c.Digest.Unsubscribe.SecretFile, "digest.unsubscribe.secret"); err != nil {
+		return err
+	}
+	return readSecretFile(&c.AI.ApiKey, c.AI.ApiKeyFile, "ai.api_key")
+}
+
 func setDefaultValues(k *koanf.Koanf) error {
 	return k.Load(confmap.Provider(map[string]any{
-		"digest.compress":     true,
-		"digest.group_by":     "day",
-		"digest.schedule":     "@weekly",
-		"digest.mark_as_read": true,
-		"digest.run_on_startup": false,
+		"email.transport":            "smtp",
+		"email.sendmail.path":        "/usr/sbin/sendmail",
+		"smtp.tls":                   "starttls_mandatory",
+		"smtp.auth":                  "auto",
+		"digest.compress":            true,
+		"digest.email.format":        "attachment",
+		"digest.group_by":            "day",
+		"digest.schedule":            "@weekly",
+		"digest.mark_as_read_policy": "on_success",
+		"digest.run_on_startup":      false,
+		"ai.provider":                "gemini",
+		"ai.timeout":                 "2m",
+		"ai.max_input_tokens":        30000,
+		"ai.cache.ttl":               "0s",
+		"ai.cache.max_size_mb":       50,
+		"outbox.max_age":             "72h",
 	}, "."), nil)
 }
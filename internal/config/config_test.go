@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -23,36 +25,814 @@ func TestLoad(t *testing.T) {
 				},
 				"digest": map[string]any{
 					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid config missing miniflux.host",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid config missing miniflux.api_token",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host": "miniflux.example.com",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid smtp.port too high",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"port": 65536,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid smtp.port",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"port": 587,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid smtp tls and auth",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"port": 465,
+					"tls":  "implicit",
+					"auth": "login",
+					"user": "digest",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid smtp.max_send_rate",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"max_send_rate": -1,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid email.transport maildir",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"email": map[string]any{
+					"transport": "maildir",
+					"maildir": map[string]any{
+						"path": "/var/mail/digest",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "missing email.maildir.path",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"email": map[string]any{
+					"transport": "maildir",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid email.transport sendmail with default path",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"email": map[string]any{
+					"transport": "sendmail",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid email.transport",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"email": map[string]any{
+					"transport": "pigeon",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid smtp.tls",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"tls": "ssl",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid smtp.auth",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"auth": "oauth",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing smtp.user for smtp.auth plain",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"auth": "plain",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing smtp.ca_file",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"ca_file": "/nonexistent/ca.pem",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "smtp.insecure_skip_verify without tls",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"tls":                  "none",
+					"insecure_skip_verify": true,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid digest.email.subject template",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"subject": "{{ .Category.Title",
+						"to":      "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing digest.email.body_template_file",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"body_template_file": "/nonexistent/body.gotxt",
+						"to":                 "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing templates.dir",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"templates": map[string]any{
+					"dir": "/nonexistent/templates",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "digest.unsubscribe.secret without digest.host",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"individual": true,
+						"to":         "reader@example.com",
+					},
+					"unsubscribe": map[string]any{
+						"secret": "secret",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid digest.unsubscribe",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"host":     "https://digest.example.com",
+					"email": map[string]any{
+						"individual": true,
+						"to":         "reader@example.com",
+					},
+					"unsubscribe": map[string]any{
+						"secret": "secret",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "digest.unsubscribe.secret without digest.email.individual",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"host":     "https://digest.example.com",
+					"unsubscribe": map[string]any{
+						"secret": "secret",
+					},
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "digest.unsubscribe.secret with a category that is not sent individually",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"host":     "https://digest.example.com",
+					"email": map[string]any{
+						"individual": true,
+						"to":         "reader@example.com",
+					},
+					"unsubscribe": map[string]any{
+						"secret": "secret",
+					},
+					"categories": map[string]any{
+						"Security": map[string]any{
+							"email": map[string]any{
+								"individual": false,
+							},
+						},
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "incomplete smtp.dkim",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"dkim": map[string]any{
+						"selector": "digest",
+						"domain":   "example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing smtp.dkim.private_key_file",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"smtp": map[string]any{
+					"dkim": map[string]any{
+						"selector":         "digest",
+						"domain":           "example.com",
+						"private_key_file": "/nonexistent/dkim.pem",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid digest.email.to format",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule":  "@daily",
+					"email": map[string]any{
+						"to": "invalid-email",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing digest.email recipients",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"email": map[string]any{
+						"from": "digest@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid digest.email.from format",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule":   "@daily",
+					"email": map[string]any{
+						"from": "another-invalid-email",
+						"to":   "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid digest.email.to and from",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule":   "@daily",
+					"email": map[string]any{
+						"to":   "test@example.com",
+						"from": "sender@example.com",
+					},
+				},
+			},
+							wantErr: false,
+		},
+		{
+			name: "valid cron schedule",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "* * 1 * *",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid cron schedule",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "* * * * * * *",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid @every schedule",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@every 1h30m",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid @every schedule",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@every bad-duration",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid group_by value",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"group_by": "magic",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid digest.mark_as_read_policy",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule":            "@daily",
+					"mark_as_read_policy": "sometimes",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid digest.mark_as_read_policy",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule":            "@daily",
+					"mark_as_read_policy": "always",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "valid digest.categories override",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"categories": map[string]any{
+						"42": map[string]any{
+							"schedule": "0 7 * * *",
+							"group_by": "feed",
+						},
+						"Long reads": map[string]any{
+							"email": map[string]any{
+								"to": "reader@example.com",
+							},
+							"mark_as_read": false,
+						},
+					},
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid digest.categories schedule",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"categories": map[string]any{
+						"42": map[string]any{
+							"schedule": "@every bad-duration",
+						},
+					},
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid digest.categories email.to",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"categories": map[string]any{
+						"42": map[string]any{
+							"email": map[string]any{
+								"to": "invalid-email",
+							},
+						},
+					},
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing ai.api_key when a category group_by is ai",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule": "@daily",
+					"categories": map[string]any{
+						"42": map[string]any{
+							"group_by": "ai",
+						},
+					},
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid digest category filters",
+			config: map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule":           "@daily",
+					"include_categories": []string{"42", "Tech*"},
+					"exclude_categories": []string{"Podcasts"},
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name: "invalid config missing miniflux.host",
+			name: "invalid digest.exclude_categories pattern",
 			config: map[string]any{
 				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
 					"api_token": "test-token",
 				},
 				"digest": map[string]any{
-					"schedule": "@daily",
+					"schedule":           "@daily",
+					"exclude_categories": []string{"[Podcasts"},
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "invalid config missing miniflux.api_token",
+			name: "valid digest.email recipient lists",
 			config: map[string]any{
 				"miniflux": map[string]any{
-					"host": "miniflux.example.com",
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
 				},
 				"digest": map[string]any{
 					"schedule": "@daily",
+					"email": map[string]any{
+						"to":         []string{"one@example.com", "two@example.com"},
+						"cc":         "three@example.com",
+						"bcc":        []string{"four@example.com"},
+						"individual": true,
+					},
 				},
 			},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
-			name: "invalid smtp.port too high",
+			name: "invalid digest.email.cc address",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
@@ -60,15 +840,15 @@ func TestLoad(t *testing.T) {
 				},
 				"digest": map[string]any{
 					"schedule": "@daily",
-				},
-				"smtp": map[string]any{
-					"port": 65536,
+					"email": map[string]any{
+						"cc": []string{"one@example.com", "invalid-email"},
+					},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "valid smtp.port",
+			name: "valid digest.email.format",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
@@ -76,116 +856,158 @@ func TestLoad(t *testing.T) {
 				},
 				"digest": map[string]any{
 					"schedule": "@daily",
-				},
-				"smtp": map[string]any{
-					"port": 587,
+					"email": map[string]any{
+						"format": "both",
+						"to":     "reader@example.com",
+					},
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name: "invalid digest.email.to format",
+			name: "invalid digest.email.format",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
 					"api_token": "test-token",
 				},
 				"digest": map[string]any{
-					"schedule":  "@daily",
+					"schedule": "@daily",
 					"email": map[string]any{
-						"to": "invalid-email",
+						"format": "pdf",
+						"to":     "reader@example.com",
 					},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "invalid digest.email.from format",
+			name: "missing ai.api_key when group_by is ai",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
 					"api_token": "test-token",
 				},
 				"digest": map[string]any{
-					"schedule":   "@daily",
+					"schedule": "@daily",
+					"group_by": "ai",
 					"email": map[string]any{
-						"from": "another-invalid-email",
+						"to": "reader@example.com",
 					},
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "valid digest.email.to and from",
+			name: "missing ai.api_key when ai.entry_summaries is set",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
 					"api_token": "test-token",
 				},
 				"digest": map[string]any{
-					"schedule":   "@daily",
+					"schedule": "@daily",
 					"email": map[string]any{
-						"to":   "test@example.com",
-						"from": "sender@example.com",
+						"to": "reader@example.com",
 					},
 				},
+				"ai": map[string]any{
+					"entry_summaries": true,
+				},
 			},
-							wantErr: false,
+			wantErr: true,
 		},
 		{
-			name: "valid cron schedule",
+			name: "openai compatible ai.provider without ai.api_key",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
 					"api_token": "test-token",
 				},
 				"digest": map[string]any{
-					"schedule": "* * 1 * *",
+					"schedule": "@daily",
+					"group_by": "ai",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"ai": map[string]any{
+					"provider": "openai",
+					"base_url": "http://localhost:11434/v1",
+					"model":    "llama3.1",
 				},
 			},
 			wantErr: false,
 		},
 		{
-			name: "invalid cron schedule",
+			name: "valid ai generation settings",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
 					"api_token": "test-token",
 				},
 				"digest": map[string]any{
-					"schedule": "* * * * * * *",
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"ai": map[string]any{
+					"model":             "gemini-2.5-pro",
+					"temperature":       0.2,
+					"max_output_tokens": 4096,
+					"timeout":           "5m",
+					"max_input_tokens":  8000,
+					"cache": map[string]any{
+						"ttl":         "1h",
+						"max_size_mb": 10,
+					},
 				},
 			},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
-			name: "valid @every schedule",
+			name: "invalid ai.cache.ttl",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
 					"api_token": "test-token",
 				},
 				"digest": map[string]any{
-					"schedule": "@every 1h30m",
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"ai": map[string]any{
+					"cache": map[string]any{
+						"ttl": "-1h",
+					},
 				},
 			},
-			wantErr: false,
+			wantErr: true,
 		},
 		{
-			name: "invalid @every schedule",
+			name: "invalid ai.temperature",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
 					"api_token": "test-token",
 				},
 				"digest": map[string]any{
-					"schedule": "@every bad-duration",
+					"schedule": "@daily",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"ai": map[string]any{
+					"temperature": 3,
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "invalid group_by value",
+			name: "invalid ai.max_output_tokens",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
@@ -193,13 +1015,18 @@ func TestLoad(t *testing.T) {
 				},
 				"digest": map[string]any{
 					"schedule": "@daily",
-					"group_by": "magic",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"ai": map[string]any{
+					"max_output_tokens": -1,
 				},
 			},
 			wantErr: true,
 		},
 		{
-			name: "missing ai.api_key when group_by is ai",
+			name: "invalid ai.provider",
 			config: map[string]any{
 				"miniflux": map[string]any{
 					"host":      "miniflux.example.com",
@@ -207,7 +1034,12 @@ func TestLoad(t *testing.T) {
 				},
 				"digest": map[string]any{
 					"schedule": "@daily",
-					"group_by": "ai",
+					"email": map[string]any{
+						"to": "reader@example.com",
+					},
+				},
+				"ai": map[string]any{
+					"provider": "claude",
 				},
 			},
 			wantErr: true,
@@ -248,7 +1080,391 @@ func TestLoad(t *testing.T) {
 					t.Errorf("Expected digest.schedule to be @weekly, got %s", cfg.Digest.Schedule)
 				}
 			}
+
+			if !tt.wantErr && tt.name == "valid config" && cfg.AI.Timeout != 2*time.Minute {
+				t.Errorf("Expected ai.timeout to default to 2m, got %s", cfg.AI.Timeout)
+			}
+
+			if !tt.wantErr && tt.name == "valid config" && (cfg.AI.Cache.TTL != 0 || cfg.AI.Cache.MaxSizeMB != 50) {
+				t.Errorf("Expected ai.cache to default to off and 50 MB, got %+v", cfg.AI.Cache)
+			}
+
+			if !tt.wantErr && tt.name == "valid ai generation settings" {
+				if cfg.AI.Temperature == nil || *cfg.AI.Temperature != 0.2 || cfg.AI.MaxOutputTokens != 4096 || cfg.AI.Timeout != 5*time.Minute || cfg.AI.MaxInputTokens != 8000 || cfg.AI.Cache.TTL != time.Hour || cfg.AI.Cache.MaxSizeMB != 10 {
+					t.Errorf("Unexpected ai settings: %+v", cfg.AI)
+				}
+			}
+		})
+	}
+
+}
+
+func TestLoad_CategoryOverrides(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data, err := yaml.Marshal(map[string]any{
+		"miniflux": map[string]any{
+			"host":      "miniflux.example.com",
+			"api_token": "test-token",
+		},
+		"digest": map[string]any{
+			"schedule": "@daily",
+			"email": map[string]any{
+				"to":   "team@example.com",
+				"from": "digest@example.com",
+			},
+			"categories": map[string]any{
+				"42": map[string]any{
+					"schedule": "0 7 * * *",
+					"group_by": "feed",
+					"email": map[string]any{
+						"format": "inline",
+					},
+				},
+				"Long reads": map[string]any{
+					"email": map[string]any{
+						"to": []string{"reader@example.com", "editor@example.com"},
+						"individual": true,
+					},
+					"mark_as_read_policy": "never",
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal test config: %v", err)
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	byID := cfg.Digest.Categories["42"]
+	if byID.MarkAsReadPolicy != MarkAsReadOnSuccess {
+		t.Error("Expected category override to inherit mark_as_read_policy from the global digest")
+	}
+	if !slices.Equal(byID.Email.To, []string{"team@example.com"}) {
+		t.Errorf("Expected category override to inherit email.to, got %v", byID.Email.To)
+	}
+
+	security := cfg.Digest.ForCategory(42, "Security")
+	if security.Schedule != "0 7 * * *" || security.GroupBy != "feed" {
+		t.Errorf("Expected category 42 overrides to apply, got schedule %q and group_by %q", security.Schedule, security.GroupBy)
+	}
+	if security.Email.Format != EmailFormatInline {
+		t.Errorf("Expected category 42 email.format override to apply, got %q", security.Email.Format)
+	}
+
+	longReads := cfg.ForCategory(7, "Long reads")
+	if !slices.Equal(longReads.Digest.Email.To, []string{"reader@example.com", "editor@example.com"}) || longReads.Digest.MarkAsReadPolicy != MarkAsReadNever {
+		t.Errorf("Expected title overrides to apply, got to %v and mark_as_read_policy %v", longReads.Digest.Email.To, longReads.Digest.MarkAsReadPolicy)
+	}
+	if longReads.Digest.Email.From != "digest@example.com" || longReads.Digest.Schedule != "@daily" {
+		t.Errorf("Expected unset fields to fall back to the global digest, got from %q and schedule %q", longReads.Digest.Email.From, longReads.Digest.Schedule)
+	}
+	if !slices.Equal(cfg.Digest.Email.To, []string{"team@example.com"}) {
+		t.Errorf("Expected global digest to be left untouched, got %v", cfg.Digest.Email.To)
+	}
+	if !longReads.Digest.Email.Individual || security.Email.Individual {
+		t.Errorf("Expected email.individual to only apply to Long reads")
+	}
+	if longReads.Digest.Email.Format != EmailFormatAttachment {
+		t.Errorf("Expected email.format to default to attachment, got %q", longReads.Digest.Email.Format)
+	}
+
+	other := cfg.Digest.ForCategory(1, "Other")
+	if other.Schedule != "@daily" || other.GroupBy != "day" || other.MarkAsReadPolicy != MarkAsReadOnSuccess {
+		t.Errorf("Expected categories without overrides to use the global digest, got %+v", other)
+	}
+}
+
+func TestLoad_DeprecatedMarkAsRead(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data, err := yaml.Marshal(map[string]any{
+		"miniflux": map[string]any{
+			"host":      "miniflux.example.com",
+			"api_token": "test-token",
+		},
+		"digest": map[string]any{
+			"schedule":            "@daily",
+			"mark_as_read":        false,
+			"mark_as_read_policy": "always",
+			"categories": map[string]any{
+				"42": map[string]any{
+					"mark_as_read": true,
+				},
+				"43": map[string]any{
+					"mark_as_read":        true,
+					"mark_as_read_policy": "on_success",
+				},
+			},
+			"email": map[string]any{
+				"to": "reader@example.com",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal test config: %v", err)
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Digest.MarkAsReadPolicy != MarkAsReadNever {
+		t.Errorf("Expected mark_as_read: false to map to never, got %q", cfg.Digest.MarkAsReadPolicy)
+	}
+	if policy := cfg.Digest.ForCategory(42, "").MarkAsReadPolicy; policy != MarkAsReadAlways {
+		t.Errorf("Expected mark_as_read: true to keep the global policy, got %q", policy)
+	}
+	if policy := cfg.Digest.ForCategory(43, "").MarkAsReadPolicy; policy != MarkAsReadOnSuccess {
+		t.Errorf("Expected the category policy to win, got %q", policy)
+	}
+	if policy := cfg.Digest.ForCategory(1, "").MarkAsReadPolicy; policy != MarkAsReadNever {
+		t.Errorf("Expected other categories to inherit never, got %q", policy)
+	}
+}
+
+func TestConfigDigest_IncludesCategory(t *testing.T) {
+	tests := []struct {
+		name    string
+		digest  ConfigDigest
+		id      int64
+		title   string
+		include bool
+	}{
+		{name: "no filters", digest: ConfigDigest{}, id: 1, title: "News", include: true},
+		{name: "included by id", digest: ConfigDigest{IncludeCategories: []string{"1"}}, id: 1, title: "News", include: true},
+		{name: "not included", digest: ConfigDigest{IncludeCategories: []string{"Tech"}}, id: 1, title: "News", include: false},
+		{name: "included by glob", digest: ConfigDigest{IncludeCategories: []string{"Tech*"}}, id: 2, title: "Tech News", include: true},
+		{name: "excluded by title", digest: ConfigDigest{ExcludeCategories: []string{"Podcasts"}}, id: 3, title: "Podcasts", include: false},
+		{name: "excluded by glob", digest: ConfigDigest{ExcludeCategories: []string{"*casts"}}, id: 3, title: "Podcasts", include: false},
+		{name: "exclude wins over include", digest: ConfigDigest{IncludeCategories: []string{"*"}, ExcludeCategories: []string{"3"}}, id: 3, title: "Podcasts", include: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.digest.IncludesCategory(tt.id, tt.title); got != tt.include {
+				t.Errorf("IncludesCategory(%d, %q) = %v, want %v", tt.id, tt.title, got, tt.include)
+			}
 		})
 	}
+}
+
+func TestLoad_EnvAndSecretFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	data, err := yaml.Marshal(map[string]any{
+		"miniflux": map[string]any{
+			"host": "miniflux.example.com",
+		},
+		"smtp": map[string]any{
+			"host":     "smtp.example.com",
+			"password": "yaml-password",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal test config: %v", err)
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	tokenPath := filepath.Join(tmpDir, "api_token")
+	if err := os.WriteFile(tokenPath, []byte("file-token\n"), 0600); err != nil {
+		t.Fatalf("Failed to write secret file: %v", err)
+	}
+
+	t.Setenv("MINIFLUX_DIGEST_MINIFLUX__API_TOKEN_FILE", tokenPath)
+	t.Setenv("MINIFLUX_DIGEST_SMTP__PASSWORD", "env-password")
+	t.Setenv("MINIFLUX_DIGEST_SMTP__PORT", "465")
+	t.Setenv("MINIFLUX_DIGEST_DIGEST__SCHEDULE", "@daily")
+	t.Setenv("MINIFLUX_DIGEST_DIGEST__EMAIL__TO", "a@example.com,b@example.com")
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Miniflux.ApiToken != "file-token" {
+		t.Errorf("Expected api_token to be read from file, got %q", cfg.Miniflux.ApiToken)
+	}
+	if cfg.Smtp.Password != "env-password" {
+		t.Errorf("Expected smtp.password from environment to override YAML, got %q", cfg.Smtp.Password)
+	}
+	if cfg.Smtp.Port != 465 {
+		t.Errorf("Expected smtp.port from environment, got %d", cfg.Smtp.Port)
+	}
+	if cfg.Smtp.Host != "smtp.example.com" {
+		t.Errorf("Expected smtp.host from YAML to be kept, got %q", cfg.Smtp.Host)
+	}
+	if cfg.Digest.Schedule != "@daily" {
+		t.Errorf("Expected digest.schedule from environment, got %q", cfg.Digest.Schedule)
+	}
+	if !slices.Equal(cfg.Digest.Email.To, []string{"a@example.com", "b@example.com"}) {
+		t.Errorf("Expected comma separated digest.email.to from environment, got %v", cfg.Digest.Email.To)
+	}
+
+	t.Setenv("MINIFLUX_DIGEST_SMTP__PASSWORD_FILE", filepath.Join(tmpDir, "missing"))
+	if _, err := Load(configPath); err == nil {
+		t.Error("Expected error when both smtp.password and smtp.password_file are set")
+	}
+
+	t.Setenv("MINIFLUX_DIGEST_SMTP__PASSWORD", "")
+	if _, err := Load(configPath); err == nil {
+		t.Error("Expected error when smtp.password_file does not exist")
+	}
+}
+
+func TestLoad_EnvCategoryOverrideByTitle(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data, err := yaml.Marshal(map[string]any{
+		"miniflux": map[string]any{
+			"host":      "miniflux.example.com",
+			"api_token": "test-token",
+		},
+		"digest": map[string]any{
+			"schedule": "@daily",
+			"categories": map[string]any{
+				"Security": map[string]any{
+					"schedule": "0 7 * * *",
+					"group_by": "feed",
+				},
+			},
+			"email": map[string]any{
+				"to": "reader@example.com",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal test config: %v", err)
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	t.Setenv("MINIFLUX_DIGEST_DIGEST__CATEGORIES__SECURITY__GROUP_BY", "day")
+	t.Setenv("MINIFLUX_DIGEST_DIGEST__CATEGORIES__LONG READS__SCHEDULE", "0 9 * * 6")
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	security := cfg.Digest.ForCategory(5, "Security")
+	if security.Schedule != "0 7 * * *" || security.GroupBy != "day" {
+		t.Errorf("Expected the environment to override the YAML category, got schedule %q and group_by %q", security.Schedule, security.GroupBy)
+	}
+	if longReads := cfg.Digest.ForCategory(6, "Long Reads"); longReads.Schedule != "0 9 * * 6" {
+		t.Errorf("Expected a title override from the environment to match, got schedule %q", longReads.Schedule)
+	}
+	if len(cfg.Digest.Categories) != 2 {
+		t.Errorf("Expected the YAML and environment overrides to be merged, got %v", cfg.Digest.Categories)
+	}
+}
+
+func TestLoad_CategoryTitleWithDots(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data, err := yaml.Marshal(map[string]any{
+		"miniflux": map[string]any{
+			"host":      "miniflux.example.com",
+			"api_token": "test-token",
+		},
+		"digest": map[string]any{
+			"schedule": "@daily",
+			"categories": map[string]any{
+				"Example.com News": map[string]any{
+					"schedule": "@weekly",
+					"email": map[string]any{
+						"to": []string{"news@example.com"},
+					},
+				},
+			},
+			"email": map[string]any{
+				"to": "reader@example.com",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal test config: %v", err)
+	}
+	if err := os.WriteFile(configPath, data, 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, err := Load(configPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	news := cfg.Digest.ForCategory(1, "Example.com News")
+	if news.Schedule != "@weekly" || !slices.Equal(news.Email.To, []string{"news@example.com"}) {
+		t.Errorf("Expected the override of a title with dots to apply, got schedule %q and to %v", news.Schedule, news.Email.To)
+	}
+	if _, ok := cfg.Digest.Categories["Example.com News"]; !ok || len(cfg.Digest.Categories) != 1 {
+		t.Errorf("Expected the override to be kept under its title, got %v", cfg.Digest.Categories)
+	}
+}
 
+func TestLoad_InvalidCategoryOverrides(t *testing.T) {
+	tests := []struct {
+		name       string
+		categories map[string]any
+		env        map[string]string
+	}{
+		{
+			name: "titles differing only in case",
+			categories: map[string]any{
+				"Security": map[string]any{"schedule": "@daily"},
+				"security": map[string]any{"schedule": "@weekly"},
+			},
+		},
+		{
+			name:       "unknown setting",
+			categories: map[string]any{"Security": map[string]any{"schedul": "@daily"}},
+		},
+		{
+			name:       "not a map",
+			categories: map[string]any{"Security": "@daily"},
+		},
+		{
+			name: "environment key that cannot be mapped",
+			env:  map[string]string{"MINIFLUX_DIGEST_DIGEST__CATEGORIES__EXAMPLE.COM__SCHEDULE": "@daily"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			data, err := yaml.Marshal(map[string]any{
+				"miniflux": map[string]any{
+					"host":      "miniflux.example.com",
+					"api_token": "test-token",
+				},
+				"digest": map[string]any{
+					"schedule":   "@daily",
+					"categories": tt.categories,
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to marshal test config: %v", err)
+			}
+			if err := os.WriteFile(configPath, data, 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			if _, err := Load(configPath); err == nil {
+				t.Error("Expected an error for a category override that cannot be mapped")
+			}
+		})
+	}
 }
@@ -0,0 +1,275 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/llm"
+)
+
+const (
+	DefaultMaxInputTokens = 30000
+	// MaxEntryContentChars is how much of the text of an entry is sent to the
+	// LLM, which is plenty to group it and keeps busy categories in budget.
+	MaxEntryContentChars = 2000
+)
+
+const llmMergePrompt = `You are an expert news editor. A large set of news entries was split into batches and each batch was grouped separately. Below are the summary of every batch and the groups it produced, each with an id, a title and its number of entries.
+
+Your task is to reconcile them into one digest:
+
+1.  Write one overall 'summary': a single, concise paragraph that highlights the most significant themes, trends or critical events across all batches. Do not simply concatenate the batch summaries.
+
+2.  Merge the batch groups into final 'groups': groups about the same topic must be merged, even when their titles differ slightly. Give every final group a short, descriptive title useful for skimming and list the ids of the batch groups it contains in 'groups', most important first. Every batch group id must appear in exactly one final group.
+
+Return the response as a JSON object according to the desired responseSchema.
+
+-----------------
+
+`
+
+var llmMergeSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"summary": {
+			Type: llm.TypeString,
+		},
+		"groups": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"title": {
+						Type: llm.TypeString,
+					},
+					"groups": {
+						Type: llm.TypeArray,
+						Items: &llm.Schema{
+							Type: llm.TypeInteger,
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+type llmBatchGroup struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Entries int    `json:"entries"`
+}
+
+type llmMergeResponse struct {
+	Summary string `json:"summary"`
+	Groups  []struct {
+		Title  string `json:"title"`
+		Groups []int  `json:"groups"`
+	} `json:"groups"`
+}
+
+// estimateTokens approximates the token count of a prompt, assuming about four
+// bytes per token like most tokenizers do for English text.
+func estimateTokens(s string) int {
+	return len(s)/4 + 1
+}
+
+// plainText strips the HTML of entry content and truncates it to maxChars.
+func plainText(content string, maxChars int) string {
+	var words []string
+	tokenizer := html.NewTokenizer(strings.NewReader(content))
+	skip := 0
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			text := []rune(strings.Join(words, " "))
+			if len(text) > maxChars {
+				return string(text[:maxChars]) + "…"
+			}
+			return string(text)
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "script" || string(name) == "style" {
+				skip++
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); (string(name) == "script" || string(name) == "style") && skip > 0 {
+				skip--
+			}
+		case html.TextToken:
+			if skip == 0 {
+				words = append(words, strings.Fields(string(tokenizer.Text()))...)
+			}
+		}
+	}
+}
+
+func newLLMEntries(entries *miniflux.Entries) []llmEntry {
+	llmEntries := make([]llmEntry, len(*entries))
+	for i, entry := range *entries {
+		llmEntries[i] = llmEntry{
+			ID:        entry.ID,
+			Title:     entry.Title,
+			URL:       entry.URL,
+			Content:   plainText(entry.Content, MaxEntryContentChars),
+			FeedTitle: entry.Feed.Title,
+		}
+	}
+	return llmEntries
+}
+
+// batchEntries splits entries into batches whose JSON fits in maxTokens. An
+// entry larger than the budget gets a batch of its own.
+func batchEntries(entries []llmEntry, maxTokens int) [][]llmEntry {
+	var batches [][]llmEntry
+	var batch []llmEntry
+	tokens := 0
+	for _, entry := range entries {
+		encoded, _ := json.MarshalIndent(entry, "  ", "  ")
+		entryTokens := estimateTokens(string(encoded))
+
+		if len(batch) > 0 && tokens+entryTokens > maxTokens {
+			batches = append(batches, batch)
+			batch, tokens = nil, 0
+		}
+		batch = append(batch, entry)
+		tokens += entryTokens
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// maxInputTokens returns tokens, or DefaultMaxInputTokens when it is not set.
+func maxInputTokens(tokens int) int {
+	if tokens > 0 {
+		return tokens
+	}
+	return DefaultMaxInputTokens
+}
+
+// groupBatches groups every batch and merges the results when there is more
+// than one.
+func (g *LLMGrouper) groupBatches(batches [][]llmEntry) (*LLMResponse, error) {
+	responses := make([]*LLMResponse, 0, len(batches))
+	for i, batch := range batches {
+		response, err := g.groupBatch(batch)
+		if err != nil {
+			return nil, fmt.Errorf("batch %d of %d: %w", i+1, len(batches), err)
+		}
+		responses = append(responses, response)
+	}
+
+	if len(responses) == 1 {
+		return responses[0], nil
+	}
+	return g.mergeResponses(responses), nil
+}
+
+func (g *LLMGrouper) groupBatch(batch []llmEntry) (*LLMResponse, error) {
+	entriesJSON, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	llmResponse, err := g.LLMService.GenerateContent(context.Background(), llmPrompt+string(entriesJSON), llmResponseSchema)
+	if err != nil {
+		return nil, fmt.Errorf("LLM service failed: %w", err)
+	}
+
+	var response LLMResponse
+	if err := json.Unmarshal([]byte(llmResponse), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+	}
+	return &response, nil
+}
+
+// mergeResponses asks the LLM to reconcile the groups of every batch and to
+// summarize them. When that fails, groups with the same title are merged and
+// the batch summaries are joined instead.
+func (g *LLMGrouper) mergeResponses(responses []*LLMResponse) *LLMResponse {
+	var groups []LLMGroup
+	var batchGroups []llmBatchGroup
+	var summaries []string
+	for _, response := range responses {
+		for _, group := range response.Groups {
+			batchGroups = append(batchGroups, llmBatchGroup{ID: len(groups), Title: group.Title, Entries: len(group.Entries)})
+			groups = append(groups, group)
+		}
+		if response.Summary != "" {
+			summaries = append(summaries, response.Summary)
+		}
+	}
+
+	merged, err := g.mergeGroups(summaries, batchGroups)
+	if err != nil {
+		log.Printf("LLM merge failed, merging groups by title: %v\n", err)
+		return mergeByTitle(groups, strings.Join(summaries, " "))
+	}
+
+	response := &LLMResponse{Summary: merged.Summary}
+	used := make(map[int]bool)
+	for _, mergedGroup := range merged.Groups {
+		group := LLMGroup{Title: mergedGroup.Title}
+		for _, id := range mergedGroup.Groups {
+			if id < 0 || id >= len(groups) || used[id] {
+				continue
+			}
+			used[id] = true
+			group.Entries = append(group.Entries, groups[id].Entries...)
+		}
+		if len(group.Entries) > 0 {
+			response.Groups = append(response.Groups, group)
+		}
+	}
+
+	for id, group := range groups {
+		if !used[id] {
+			response.Groups = append(response.Groups, group)
+		}
+	}
+	return response
+}
+
+func (g *LLMGrouper) mergeGroups(summaries []string, groups []llmBatchGroup) (*llmMergeResponse, error) {
+	input, err := json.MarshalIndent(struct {
+		Summaries []string        `json:"summaries"`
+		Groups    []llmBatchGroup `json:"groups"`
+	}{summaries, groups}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	llmResponse, err := g.LLMService.GenerateContent(context.Background(), llmMergePrompt+string(input), llmMergeSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	var response llmMergeResponse
+	if err := json.Unmarshal([]byte(llmResponse), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+	}
+	return &response, nil
+}
+
+func mergeByTitle(groups []LLMGroup, summary string) *LLMResponse {
+	response := &LLMResponse{Summary: summary}
+	index := make(map[string]int)
+	for _, group := range groups {
+		key := strings.ToLower(strings.TrimSpace(group.Title))
+		if i, ok := index[key]; ok {
+			response.Groups[i].Entries = append(response.Groups[i].Entries, group.Entries...)
+			continue
+		}
+		index[key] = len(response.Groups)
+		response.Groups = append(response.Groups, LLMGroup{Title: group.Title, Entries: slices.Clone(group.Entries)})
+	}
+	return response
+}
@@ -0,0 +1,147 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/llm"
+)
+
+func TestPlainText(t *testing.T) {
+	content := `<p>Go <b>1.24</b>   released</p><script>alert("x")</script><style>p{}</style><ul><li>Generic aliases</li></ul>`
+	if got := plainText(content, 100); got != "Go 1.24 released Generic aliases" {
+		t.Errorf("Unexpected plain text %q", got)
+	}
+	if got := plainText(content, 5); got != "Go 1.…" {
+		t.Errorf("Expected the text to be truncated, got %q", got)
+	}
+}
+
+func TestBatchEntries(t *testing.T) {
+	entries := []llmEntry{
+		{ID: 1, Content: strings.Repeat("a", 400)},
+		{ID: 2, Content: strings.Repeat("b", 400)},
+		{ID: 3, Content: strings.Repeat("c", 4000)},
+		{ID: 4, Content: strings.Repeat("d", 400)},
+	}
+
+	batches := batchEntries(entries, 300)
+	var sizes []int
+	for _, batch := range batches {
+		sizes = append(sizes, len(batch))
+	}
+	if fmt.Sprint(sizes) != "[2 1 1]" {
+		t.Errorf("Expected batches of 2, 1 and 1 entries, got %v", sizes)
+	}
+
+	if batches := batchEntries(entries, DefaultMaxInputTokens); len(batches) != 1 {
+		t.Errorf("Expected a single batch within budget, got %d", len(batches))
+	}
+}
+
+// batchedMockLLM groups every batch into one group with the given title and
+// answers merge prompts with merge.
+func batchedMockLLM(t *testing.T, titles []string, merge func() (string, error)) *mockLLMService {
+	batch := 0
+	return &mockLLMService{
+		GenerateContentFunc: func(ctx context.Context, prompt string, schema *llm.Schema) (string, error) {
+			if strings.HasPrefix(prompt, llmMergePrompt) {
+				return merge()
+			}
+
+			var entries []llmEntry
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(prompt, llmPrompt)), &entries); err != nil {
+				t.Fatalf("Failed to decode batch prompt: %v", err)
+			}
+			response := LLMResponse{Summary: fmt.Sprintf("Batch %d.", batch+1), Groups: []LLMGroup{{Title: titles[batch]}}}
+			for _, entry := range entries {
+				if strings.Contains(entry.Content, "<") {
+					t.Errorf("Expected entry content without HTML, got %q", entry.Content)
+				}
+				response.Groups[0].Entries = append(response.Groups[0].Entries, int(entry.ID))
+			}
+			batch++
+
+			encoded, err := json.Marshal(response)
+			return string(encoded), err
+		},
+	}
+}
+
+func largeEntries() *miniflux.Entries {
+	entries := createDayGrouperMockEntries()
+	for _, entry := range *entries {
+		entry.Content = "<p>" + strings.Repeat("word ", 300) + "</p>"
+	}
+	return entries
+}
+
+func TestLLMGrouper_GroupEntriesBatched(t *testing.T) {
+	mockLLM := batchedMockLLM(t, []string{"Go", "Golang", "Python", "Go tooling"}, func() (string, error) {
+		return `{"summary": "Overall summary.", "groups": [{"title": "Go", "groups": [0, 1, 3]}, {"title": "Python", "groups": [2, 7]}]}`, nil
+	})
+
+	grouper := &LLMGrouper{LLMService: mockLLM, MaxInputTokens: estimateTokens(llmPrompt) + 500}
+	groups, summary := grouper.GroupEntries(largeEntries())
+
+	if summary != "Overall summary." {
+		t.Errorf("Expected the merged summary, got %q", summary)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 merged groups, got %d", len(groups))
+	}
+
+	goGroup := findGroup(groups, "Go")
+	if goGroup == nil || len(goGroup.Entries) != 3 || goGroup.Entries[0].ID != 1 || goGroup.Entries[1].ID != 2 || goGroup.Entries[2].ID != 4 {
+		t.Errorf("Incorrect Go group: %+v", goGroup)
+	}
+	pythonGroup := findGroup(groups, "Python")
+	if pythonGroup == nil || len(pythonGroup.Entries) != 1 || pythonGroup.Entries[0].ID != 3 {
+		t.Errorf("Incorrect Python group: %+v", pythonGroup)
+	}
+}
+
+func TestLLMGrouper_GroupEntriesMergeFallback(t *testing.T) {
+	mockLLM := batchedMockLLM(t, []string{"Go", "Python", "go ", "Rust"}, func() (string, error) {
+		return "", errors.New("LLM API error")
+	})
+
+	grouper := &LLMGrouper{LLMService: mockLLM, MaxInputTokens: estimateTokens(llmPrompt) + 500}
+	groups, summary := grouper.GroupEntries(largeEntries())
+
+	if summary != "Batch 1. Batch 2. Batch 3. Batch 4." {
+		t.Errorf("Expected the batch summaries to be joined, got %q", summary)
+	}
+	if len(groups) != 3 {
+		t.Fatalf("Expected groups with the same title to be merged, got %d groups", len(groups))
+	}
+	if goGroup := findGroup(groups, "Go"); goGroup == nil || len(goGroup.Entries) != 2 {
+		t.Errorf("Incorrect Go group: %+v", goGroup)
+	}
+}
+
+func TestLLMGrouper_GroupEntriesBatchFailure(t *testing.T) {
+	calls := 0
+	mockLLM := &mockLLMService{
+		GenerateContentFunc: func(ctx context.Context, prompt string, schema *llm.Schema) (string, error) {
+			calls++
+			return "", errors.New("LLM API error")
+		},
+	}
+
+	grouper := &LLMGrouper{LLMService: mockLLM, MaxInputTokens: estimateTokens(llmPrompt) + 500}
+	groups, summary := grouper.GroupEntries(largeEntries())
+
+	if calls != 1 {
+		t.Errorf("Expected grouping to stop at the first failed batch, got %d calls", calls)
+	}
+	if len(groups) == 0 || !strings.HasPrefix(summary, "You have 4 entries") {
+		t.Errorf("Expected fallback to day grouping, got %q", summary)
+	}
+}
@@ -1,8 +1,6 @@
 package digest
 
 import (
-	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"miniflux-digest/internal/llm"
@@ -11,7 +9,6 @@ import (
 	"time"
 	"strings"
 
-	"google.golang.org/genai"
 	miniflux "miniflux.app/v2/client"
 )
 
@@ -23,7 +20,6 @@ const (
 )
 
 const (
-	LLMTimeout        = 2 * time.Minute
 	DayGroupLayout    = "2006-01-02"
 	DayGroupTitleLayout = "Jan 2, 2006"
 )
@@ -34,10 +30,35 @@ func (gt GroupingType) String() string {
 
 type DigestService struct{
 	LLMService llm.LLMService
+	MaxInputTokens int
+	EntrySummaries bool
+	SummaryCache *SummaryCache
 }
 
-func NewDigestService(llmService llm.LLMService) *DigestService {
-	return &DigestService{LLMService: llmService}
+type DigestServiceOption func(*DigestService)
+
+// WithEntrySummaries has the LLM write a TL;DR of every entry, reusing the
+// ones in cache when it is not nil.
+func WithEntrySummaries(cache *SummaryCache) DigestServiceOption {
+	return func(s *DigestService) {
+		s.EntrySummaries = true
+		s.SummaryCache = cache
+	}
+}
+
+// WithMaxInputTokens sets the prompt size LLM grouping batches entries to.
+func WithMaxInputTokens(tokens int) DigestServiceOption {
+	return func(s *DigestService) {
+		s.MaxInputTokens = tokens
+	}
+}
+
+func NewDigestService(llmService llm.LLMService, opts ...DigestServiceOption) *DigestService {
+	s := &DigestService{LLMService: llmService}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func NewGrouper(groupBy GroupingType, llmService llm.LLMService) Grouper {
@@ -60,8 +81,20 @@ func (s *DigestService) BuildDigestData(category *miniflux.Category, entries *mi
 
 	// Group entries
 	grouper := NewGrouper(groupBy, s.LLMService)
+	if llmGrouper, ok := grouper.(*LLMGrouper); ok {
+		llmGrouper.MaxInputTokens = s.MaxInputTokens
+	}
 	entryGroups, summary := grouper.GroupEntries(entries)
 
+	if s.EntrySummaries && s.LLMService != nil {
+		tldrs := s.summarizeEntries(entries)
+		for _, group := range entryGroups {
+			for _, entry := range group.Entries {
+				entry.TLDR = tldrs[entry.ID]
+			}
+		}
+	}
+
 	return &models.HTMLTemplateData{
 		Category:      category,
 		Entries:       entries,
@@ -86,10 +119,10 @@ func (g *DayGrouper) GroupEntries(entries *miniflux.Entries) ([]*models.EntryGro
 		if _, ok := entryGroupsMap[dateKey]; !ok {
 			entryGroupsMap[dateKey] = &models.EntryGroup{
 				Title:   entry.Date.Format(DayGroupTitleLayout),
-				Entries: []*miniflux.Entry{},
+				Entries: []*models.Entry{},
 			}
 		}
-		entryGroupsMap[dateKey].Entries = append(entryGroupsMap[dateKey].Entries, entry)
+		entryGroupsMap[dateKey].Entries = append(entryGroupsMap[dateKey].Entries, &models.Entry{Entry: entry})
 	}
 
 	// Convert map to sorted slice of EntryGroups
@@ -124,10 +157,10 @@ func (g *FeedGrouper) GroupEntries(entries *miniflux.Entries) ([]*models.EntryGr
 		if _, ok := entryGroupsMap[entry.FeedID]; !ok {
 			entryGroupsMap[entry.FeedID] = &models.EntryGroup{
 				Title:   entry.Feed.Title,
-				Entries: []*miniflux.Entry{},
+				Entries: []*models.Entry{},
 			}
 		}
-		entryGroupsMap[entry.FeedID].Entries = append(entryGroupsMap[entry.FeedID].Entries, entry)
+		entryGroupsMap[entry.FeedID].Entries = append(entryGroupsMap[entry.FeedID].Entries, &models.Entry{Entry: entry})
 	}
 
 	// Convert map to slice of EntryGroups
@@ -153,14 +186,19 @@ func (g *FeedGrouper) GroupEntries(entries *miniflux.Entries) ([]*models.EntryGr
 
 type LLMGrouper struct {
 	LLMService llm.LLMService
+	// MaxInputTokens is the prompt size entries are batched to, 0 uses
+	// DefaultMaxInputTokens.
+	MaxInputTokens int
+}
+
+type LLMGroup struct {
+	Title   string `json:"title"`
+	Entries []int  `json:"entries"`
 }
 
 type LLMResponse struct {
-	Summary string `json:"summary"`
-	Groups  []struct {
-		Title   string `json:"title"`
-		Entries []int  `json:"entries"`
-	} `json:"groups"`
+	Summary string     `json:"summary"`
+	Groups  []LLMGroup `json:"groups"`
 }
 
 type llmEntry struct {
@@ -194,24 +232,24 @@ Below are the entries and other relevant metadata for this task:
 `
 
 
-var llmResponseSchema = &genai.Schema{
-	Type: genai.TypeObject,
-	Properties: map[string]*genai.Schema{
+var llmResponseSchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
 		"summary": {
-			Type: genai.TypeString,
+			Type: llm.TypeString,
 		},
 		"groups": {
-			Type: genai.TypeArray,
-			Items: &genai.Schema{
-				Type: genai.TypeObject,
-				Properties: map[string]*genai.Schema{
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
 					"title": {
-						Type: genai.TypeString,
+						Type: llm.TypeString,
 					},
 					"entries": {
-						Type: genai.TypeArray,
-						Items: &genai.Schema{
-							Type: genai.TypeInteger,
+						Type: llm.TypeArray,
+						Items: &llm.Schema{
+							Type: llm.TypeInteger,
 						},
 					},
 				},
@@ -221,50 +259,24 @@ var llmResponseSchema = &genai.Schema{
 }
 
 func (g *LLMGrouper) GroupEntries(entries *miniflux.Entries) ([]*models.EntryGroup, string) {
-	llmEntries := make([]llmEntry, len(*entries))
-	for i, entry := range *entries {
-		llmEntries[i] = llmEntry{
-			ID:        entry.ID,
-			Title:     entry.Title,
-			URL:       entry.URL,
-			Content:   entry.Content,
-			FeedTitle: entry.Feed.Title,
-		}
-	}
-
-	entriesJSON, err := json.MarshalIndent(llmEntries, "", "  ")
-	if err != nil {
-		return (&DayGrouper{}).GroupEntries(entries)
-	}
-
-	prompt := llmPrompt + string(entriesJSON)
-
-	ctx, cancel := context.WithTimeout(context.Background(), LLMTimeout)
-	defer cancel()
-
-	llmResponse, err := g.LLMService.GenerateContent(ctx, prompt, llmResponseSchema)
+	batches := batchEntries(newLLMEntries(entries), maxInputTokens(g.MaxInputTokens)-estimateTokens(llmPrompt))
 
+	response, err := g.groupBatches(batches)
 	if err != nil {
-		log.Printf("LLM service failed, falling back to day grouping: %v\n", err)
-		return (&DayGrouper{}).GroupEntries(entries)
-	}
-
-	var response LLMResponse
-	if err := json.Unmarshal([]byte(llmResponse), &response); err != nil {
-		log.Printf("Failed to parse LLM response, falling back to day grouping: %v\n", err)
+		log.Printf("LLM grouping failed, falling back to day grouping: %v\n", err)
 		return (&DayGrouper{}).GroupEntries(entries)
 	}
 
-	entryMap := make(map[int64]*miniflux.Entry)
+	entryMap := make(map[int64]*models.Entry)
 	for _, entry := range *entries {
-		entryMap[entry.ID] = entry
+		entryMap[entry.ID] = &models.Entry{Entry: entry}
 	}
 
 	var entryGroups []*models.EntryGroup
 	groupedEntryIDs := make(map[int64]bool)
 
 	for _, groupData := range response.Groups {
-		var groupEntries []*miniflux.Entry
+		var groupEntries []*models.Entry
 		for _, entryID := range groupData.Entries {
 			if entry, ok := entryMap[int64(entryID)]; ok {
 				groupEntries = append(groupEntries, entry)
@@ -277,10 +289,10 @@ func (g *LLMGrouper) GroupEntries(entries *miniflux.Entries) ([]*models.EntryGro
 		})
 	}
 
-	var ungroupedEntries []*miniflux.Entry
+	var ungroupedEntries []*models.Entry
 	for _, entry := range *entries {
 		if !groupedEntryIDs[entry.ID] {
-			ungroupedEntries = append(ungroupedEntries, entry)
+			ungroupedEntries = append(ungroupedEntries, entryMap[entry.ID])
 		}
 	}
 
@@ -3,16 +3,16 @@ package digest
 import (
 	"context"
 	"errors"
+	"miniflux-digest/internal/llm"
 	"miniflux-digest/internal/models"
 	"testing"
 	"time"
 
-	"google.golang.org/genai"
 	miniflux "miniflux.app/v2/client"
 )
 
 type mockLLMService struct {
-	GenerateContentFunc func(ctx context.Context, prompt string, schema *genai.Schema) (string, error)
+	GenerateContentFunc func(ctx context.Context, prompt string, schema *llm.Schema) (string, error)
 }
 
 func findGroup(groups []*models.EntryGroup, title string) *models.EntryGroup {
@@ -24,7 +24,7 @@ func findGroup(groups []*models.EntryGroup, title string) *models.EntryGroup {
 	return nil
 }
 
-func (m *mockLLMService) GenerateContent(ctx context.Context, prompt string, schema *genai.Schema) (string, error) {
+func (m *mockLLMService) GenerateContent(ctx context.Context, prompt string, schema *llm.Schema) (string, error) {
 	if m.GenerateContentFunc != nil {
 		return m.GenerateContentFunc(ctx, prompt, schema)
 	}
@@ -191,7 +191,7 @@ func TestLLMGrouper_GroupEntries(t *testing.T) {
 }`
 
 	mockLLM := &mockLLMService{
-		GenerateContentFunc: func(ctx context.Context, prompt string, schema *genai.Schema) (string, error) {
+		GenerateContentFunc: func(ctx context.Context, prompt string, schema *llm.Schema) (string, error) {
 			return expectedLLMResponse, nil
 		},
 	}
@@ -219,7 +219,7 @@ func TestLLMGrouper_GroupEntries(t *testing.T) {
 	}
 
 	// Test fallback to DayGrouper on LLM error
-	mockLLM.GenerateContentFunc = func(ctx context.Context, prompt string, schema *genai.Schema) (string, error) {
+	mockLLM.GenerateContentFunc = func(ctx context.Context, prompt string, schema *llm.Schema) (string, error) {
 		return "", errors.New("LLM API error")
 	}
 	groups, summary = grouper.GroupEntries(entries)
@@ -228,7 +228,7 @@ func TestLLMGrouper_GroupEntries(t *testing.T) {
 	}
 
 	// Test fallback to DayGrouper on invalid JSON
-	mockLLM.GenerateContentFunc = func(ctx context.Context, prompt string, schema *genai.Schema) (string, error) {
+	mockLLM.GenerateContentFunc = func(ctx context.Context, prompt string, schema *llm.Schema) (string, error) {
 		return "invalid json", nil
 	}
 	groups, summary = grouper.GroupEntries(entries)
@@ -246,7 +246,7 @@ func TestLLMGrouper_GroupEntries(t *testing.T) {
 		}
 	]
 }`
-	mockLLM.GenerateContentFunc = func(ctx context.Context, prompt string, schema *genai.Schema) (string, error) {
+	mockLLM.GenerateContentFunc = func(ctx context.Context, prompt string, schema *llm.Schema) (string, error) {
 		return expectedLLMResponseWithMissingEntry, nil
 	}
 	groups, _ = grouper.GroupEntries(entries)
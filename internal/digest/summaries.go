@@ -0,0 +1,227 @@
+package digest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/llm"
+	"miniflux-digest/internal/utils"
+)
+
+// SummaryCacheMaxAge is how long a TL;DR is cached. Entries are usually read
+// well before and never summarized again.
+const SummaryCacheMaxAge = 30 * 24 * time.Hour
+
+const entrySummaryPrompt = `You are an expert news editor. Your goal is to save the user time by telling them what each entry is about before they decide to read it.
+
+For every entry below, write a 'summary' of one or two short sentences that states its key point or finding. Do not start with phrases like "This article" or "The author", do not repeat the title and do not add opinions of your own. Return the 'id' of the entry with its summary and include every entry exactly once.
+
+Return the response as a JSON object according to the desired responseSchema.
+
+Below are the entries:
+-----------------
+
+`
+
+var entrySummarySchema = &llm.Schema{
+	Type: llm.TypeObject,
+	Properties: map[string]*llm.Schema{
+		"summaries": {
+			Type: llm.TypeArray,
+			Items: &llm.Schema{
+				Type: llm.TypeObject,
+				Properties: map[string]*llm.Schema{
+					"id": {
+						Type: llm.TypeInteger,
+					},
+					"summary": {
+						Type: llm.TypeString,
+					},
+				},
+			},
+		},
+	},
+}
+
+type entrySummaryResponse struct {
+	Summaries []struct {
+		ID      int64  `json:"id"`
+		Summary string `json:"summary"`
+	} `json:"summaries"`
+}
+
+// summarizeEntries returns the TL;DR of every entry the LLM summarized keyed
+// by entry ID. Cached summaries are reused and batches that fail are logged
+// and left without one.
+func (s *DigestService) summarizeEntries(entries *miniflux.Entries) map[int64]string {
+	summaries := make(map[int64]string)
+	keys := make(map[int64]string)
+	var missing miniflux.Entries
+	for _, entry := range *entries {
+		keys[entry.ID] = summaryKey(entry)
+		if summary, ok := s.SummaryCache.Get(keys[entry.ID]); ok {
+			summaries[entry.ID] = summary
+			continue
+		}
+		missing = append(missing, entry)
+	}
+
+	if len(missing) > 0 {
+		generated := make(map[string]string)
+		batches := batchEntries(newLLMEntries(&missing), maxInputTokens(s.MaxInputTokens)-estimateTokens(entrySummaryPrompt))
+		for i, batch := range batches {
+			batchSummaries, err := s.summarizeBatch(batch)
+			if err != nil {
+				log.Printf("Error summarizing entries, batch %d of %d: %v\n", i+1, len(batches), err)
+				continue
+			}
+			for id, summary := range batchSummaries {
+				summaries[id] = summary
+				generated[keys[id]] = summary
+			}
+		}
+
+		if err := s.SummaryCache.Put(generated); err != nil {
+			log.Printf("Error caching entry summaries: %v\n", err)
+		}
+	}
+
+	return summaries
+}
+
+func (s *DigestService) summarizeBatch(batch []llmEntry) (map[int64]string, error) {
+	entriesJSON, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	// The SummaryCache keeps every TL;DR on its own, the response of a whole
+	// batch would only be reused for the exact same entries.
+	llmResponse, err := s.LLMService.GenerateContent(llm.WithoutCache(context.Background()), entrySummaryPrompt+string(entriesJSON), entrySummarySchema)
+	if err != nil {
+		return nil, fmt.Errorf("LLM service failed: %w", err)
+	}
+
+	var response entrySummaryResponse
+	if err := json.Unmarshal([]byte(llmResponse), &response); err != nil {
+		return nil, fmt.Errorf("failed to parse LLM response: %w", err)
+	}
+
+	inBatch := make(map[int64]bool, len(batch))
+	for _, entry := range batch {
+		inBatch[entry.ID] = true
+	}
+
+	summaries := make(map[int64]string)
+	for _, summary := range response.Summaries {
+		text := strings.Join(strings.Fields(summary.Summary), " ")
+		if inBatch[summary.ID] && text != "" {
+			summaries[summary.ID] = text
+		}
+	}
+	return summaries, nil
+}
+
+// summaryKey identifies an entry by its ID and content, so an entry that is
+// updated by its feed gets summarized again.
+func summaryKey(entry *miniflux.Entry) string {
+	hash := sha256.Sum256(fmt.Appendf(nil, "%d\x00%s\x00%s", entry.ID, entry.Title, entry.Content))
+	return hex.EncodeToString(hash[:])
+}
+
+type cachedSummary struct {
+	Summary   string    `json:"summary"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SummaryCache keeps entry TL;DRs in a JSON file, so entries that stay unread
+// or digests that are sent again are not summarized twice. It owns the
+// TL;DRs, summary prompts bypass the llm.CachedService. A nil cache keeps
+// nothing.
+type SummaryCache struct {
+	path      string
+	mu        sync.Mutex
+	summaries map[string]cachedSummary
+}
+
+func NewSummaryCache(dir string) (*SummaryCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create summary cache directory: %w", err)
+	}
+
+	c := &SummaryCache{
+		path:      filepath.Join(dir, "entry-summaries.json"),
+		summaries: make(map[string]cachedSummary),
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read summary cache: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &c.summaries); err != nil {
+			return nil, fmt.Errorf("failed to decode summary cache %s: %w", c.path, err)
+		}
+	}
+	return c, nil
+}
+
+func (c *SummaryCache) Get(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cached, ok := c.summaries[key]
+	if !ok || time.Since(cached.CreatedAt) > SummaryCacheMaxAge {
+		return "", false
+	}
+	return cached.Summary, true
+}
+
+// Put adds summaries to the cache, drops the expired ones and writes it to
+// disk.
+func (c *SummaryCache) Put(summaries map[string]string) error {
+	if c == nil || len(summaries) == 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, summary := range summaries {
+		c.summaries[key] = cachedSummary{Summary: summary, CreatedAt: now}
+	}
+	for key, cached := range c.summaries {
+		if now.Sub(cached.CreatedAt) > SummaryCacheMaxAge {
+			delete(c.summaries, key)
+		}
+	}
+	return c.write()
+}
+
+// write replaces the file atomically, so a crash never leaves a partially
+// written cache behind.
+func (c *SummaryCache) write() error {
+	data, err := json.Marshal(c.summaries)
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(c.path, data)
+}
@@ -0,0 +1,157 @@
+package digest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/llm"
+	"miniflux-digest/internal/models"
+)
+
+// summaryMockLLM summarizes every entry of a prompt as "TL;DR <id>" and counts
+// the entries it was asked about.
+func summaryMockLLM(t *testing.T, summarized *int) *mockLLMService {
+	return &mockLLMService{
+		GenerateContentFunc: func(ctx context.Context, prompt string, schema *llm.Schema) (string, error) {
+			if !strings.HasPrefix(prompt, entrySummaryPrompt) {
+				return "", errors.New("not a summary prompt")
+			}
+
+			var entries []llmEntry
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(prompt, entrySummaryPrompt)), &entries); err != nil {
+				t.Fatalf("Failed to decode summary prompt: %v", err)
+			}
+			*summarized += len(entries)
+
+			var response entrySummaryResponse
+			response.Summaries = make([]struct {
+				ID      int64  `json:"id"`
+				Summary string `json:"summary"`
+			}, len(entries))
+			for i, entry := range entries {
+				response.Summaries[i].ID = entry.ID
+				response.Summaries[i].Summary = fmt.Sprintf("  TL;DR\n %d ", entry.ID)
+			}
+			// An entry that is not part of the batch is ignored.
+			response.Summaries = append(response.Summaries, response.Summaries[0])
+			response.Summaries[len(response.Summaries)-1].ID = 999
+
+			data, _ := json.Marshal(response)
+			return string(data), nil
+		},
+	}
+}
+
+// entryTLDRs returns the TL;DR of every grouped entry that has one.
+func entryTLDRs(data *models.HTMLTemplateData) map[int64]string {
+	tldrs := make(map[int64]string)
+	for _, group := range data.EntryGroups {
+		for _, entry := range group.Entries {
+			if entry.TLDR != "" {
+				tldrs[entry.ID] = entry.TLDR
+			}
+		}
+	}
+	return tldrs
+}
+
+func TestBuildDigestDataEntrySummaries(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewSummaryCache(dir)
+	if err != nil {
+		t.Fatalf("NewSummaryCache() error = %v", err)
+	}
+
+	summarized := 0
+	service := NewDigestService(summaryMockLLM(t, &summarized), WithEntrySummaries(cache))
+	entries := createDayGrouperMockEntries()
+
+	data := service.BuildDigestData(&miniflux.Category{ID: 1}, entries, nil, GroupingTypeDay, "")
+	tldrs := entryTLDRs(data)
+	if len(tldrs) != len(*entries) {
+		t.Fatalf("Expected %d entry summaries, got %d", len(*entries), len(tldrs))
+	}
+	for _, entry := range *entries {
+		if got, want := tldrs[entry.ID], fmt.Sprintf("TL;DR %d", entry.ID); got != want {
+			t.Errorf("Expected summary %q for entry %d, got %q", want, entry.ID, got)
+		}
+	}
+	if !data.HasTLDRs() {
+		t.Error("Expected the digest to report its TL;DRs")
+	}
+
+	// A new run, even after a restart, only summarizes changed entries.
+	cache, err = NewSummaryCache(dir)
+	if err != nil {
+		t.Fatalf("NewSummaryCache() error = %v", err)
+	}
+	service.SummaryCache = cache
+	(*entries)[0].Content = "Updated content"
+	summarized = 0
+
+	data = service.BuildDigestData(&miniflux.Category{ID: 1}, entries, nil, GroupingTypeDay, "")
+	if summarized != 1 {
+		t.Errorf("Expected only the updated entry to be summarized again, got %d", summarized)
+	}
+	if tldrs := entryTLDRs(data); len(tldrs) != len(*entries) {
+		t.Errorf("Expected %d entry summaries, got %d", len(*entries), len(tldrs))
+	}
+}
+
+func TestBuildDigestDataEntrySummariesDisabled(t *testing.T) {
+	summarized := 0
+	service := NewDigestService(summaryMockLLM(t, &summarized))
+
+	data := service.BuildDigestData(&miniflux.Category{ID: 1}, createDayGrouperMockEntries(), nil, GroupingTypeDay, "")
+	if data.HasTLDRs() || summarized != 0 {
+		t.Errorf("Expected no entry summaries, got %d", len(entryTLDRs(data)))
+	}
+}
+
+func TestSummarizeEntriesFailure(t *testing.T) {
+	service := NewDigestService(&mockLLMService{
+		GenerateContentFunc: func(ctx context.Context, prompt string, schema *llm.Schema) (string, error) {
+			return "", errors.New("LLM error")
+		},
+	}, WithEntrySummaries(nil))
+
+	if summaries := service.summarizeEntries(createDayGrouperMockEntries()); len(summaries) != 0 {
+		t.Errorf("Expected no summaries when the LLM fails, got %d", len(summaries))
+	}
+}
+
+func TestSummaryCacheExpiry(t *testing.T) {
+	cache, err := NewSummaryCache(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewSummaryCache() error = %v", err)
+	}
+	cache.summaries["old"] = cachedSummary{Summary: "old", CreatedAt: time.Now().Add(-SummaryCacheMaxAge - time.Hour)}
+
+	if _, ok := cache.Get("old"); ok {
+		t.Error("Expected an expired summary to be a cache miss")
+	}
+	if err := cache.Put(map[string]string{"new": "new"}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, ok := cache.summaries["old"]; ok {
+		t.Error("Expected Put to drop expired summaries")
+	}
+	if summary, ok := cache.Get("new"); !ok || summary != "new" {
+		t.Errorf("Expected a cached summary, got %q, %v", summary, ok)
+	}
+
+	var nilCache *SummaryCache
+	if err := nilCache.Put(map[string]string{"key": "summary"}); err != nil {
+		t.Errorf("Expected a nil cache to ignore Put, got %v", err)
+	}
+	if _, ok := nilCache.Get("key"); ok {
+		t.Error("Expected a nil cache to miss")
+	}
+}
@@ -0,0 +1,120 @@
+package email
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/emersion/go-msgauth/dkim"
+	"github.com/wneessen/go-mail"
+
+	"miniflux-digest/internal/config"
+)
+
+const (
+	dkimMiddlewareType mail.MiddlewareType = "dkim"
+	dkimHeader         mail.Header         = "DKIM-Signature"
+)
+
+// dkimHeaderKeys are the headers covered by the signature. Listing them
+// explicitly keeps an earlier DKIM-Signature out of the signed data when a
+// message is written again, for example after an SMTP reconnect.
+var dkimHeaderKeys = []string{
+	"From", "To", "Cc", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type",
+	"List-Unsubscribe", "List-Unsubscribe-Post",
+}
+
+// dkimSigner is a go-mail middleware that adds a DKIM-Signature header to
+// every message when it is written.
+type dkimSigner struct {
+	options *dkim.SignOptions
+}
+
+func newDKIMSigner(cfg *config.ConfigDKIM) (*dkimSigner, error) {
+	key, err := loadDKIMKey(cfg.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dkimSigner{options: &dkim.SignOptions{
+		Domain:                 cfg.Domain,
+		Selector:               cfg.Selector,
+		Signer:                 key,
+		HeaderCanonicalization: dkim.CanonicalizationRelaxed,
+		BodyCanonicalization:   dkim.CanonicalizationRelaxed,
+		HeaderKeys:             dkimHeaderKeys,
+	}}, nil
+}
+
+// loadDKIMKey reads an RSA or Ed25519 private key from a PKCS#8 or PKCS#1
+// PEM file.
+func loadDKIMKey(path string) (crypto.Signer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read smtp.dkim.private_key_file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in smtp.dkim.private_key_file %s", path)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse smtp.dkim.private_key_file: %w", err)
+	}
+
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return key, nil
+	case ed25519.PrivateKey:
+		return key, nil
+	default:
+		return nil, errors.New("smtp.dkim.private_key_file must contain an RSA or Ed25519 key")
+	}
+}
+
+func (d *dkimSigner) Type() mail.MiddlewareType {
+	return dkimMiddlewareType
+}
+
+// Handle signs the message as it will be written. go-mail keeps the date,
+// message id and multipart boundaries of a message once it has been written,
+// so the signed output matches the one that is sent.
+func (d *dkimSigner) Handle(message *mail.Msg) *mail.Msg {
+	var buf bytes.Buffer
+	if _, err := message.WriteToSkipMiddleware(&buf, dkimMiddlewareType); err != nil {
+		log.Printf("Error writing message for DKIM signing: %v", err)
+		return message
+	}
+
+	signer, err := dkim.NewSigner(d.options)
+	if err != nil {
+		log.Printf("Error creating DKIM signer: %v", err)
+		return message
+	}
+	if _, err := signer.Write(buf.Bytes()); err != nil {
+		log.Printf("Error DKIM signing message: %v", err)
+		return message
+	}
+	if err := signer.Close(); err != nil {
+		log.Printf("Error DKIM signing message: %v", err)
+		return message
+	}
+
+	signature := strings.TrimPrefix(signer.Signature(), string(dkimHeader)+": ")
+	message.SetGenHeaderPreformatted(dkimHeader, strings.TrimRight(signature, "\r\n"))
+	return message
+}
@@ -0,0 +1,142 @@
+package email
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/emersion/go-msgauth/dkim"
+
+	"miniflux-digest/internal/config"
+)
+
+func writeTestKey(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dkim.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatalf("Failed to write key: %v", err)
+	}
+	return path
+}
+
+func TestDKIMSigning(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	rsaPublic, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal RSA public key: %v", err)
+	}
+
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	edDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal Ed25519 key: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		keyFile   string
+		algorithm string
+		publicKey []byte
+	}{
+		{"rsa pkcs1", writeTestKey(t, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey)), "rsa", rsaPublic},
+		{"ed25519 pkcs8", writeTestKey(t, "PRIVATE KEY", edDER), "ed25519", edPublic},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			maildir := filepath.Join(t.TempDir(), "Maildir")
+			cfg := newTransportTestConfig(config.ConfigEmail{
+				Transport: config.EmailTransportMaildir,
+				Maildir:   config.ConfigMaildir{Path: maildir},
+			})
+			cfg.Smtp.DKIM = config.ConfigDKIM{Selector: "digest", Domain: "example.com", PrivateKeyFile: tt.keyFile}
+
+			sendTestDigest(t, cfg)
+
+			delivered, err := os.ReadDir(filepath.Join(maildir, "new"))
+			if err != nil || len(delivered) != 1 {
+				t.Fatalf("Expected one delivered message, got %d: %v", len(delivered), err)
+			}
+			message, err := os.ReadFile(filepath.Join(maildir, "new", delivered[0].Name()))
+			if err != nil {
+				t.Fatalf("Failed to read message: %v", err)
+			}
+
+			record := "v=DKIM1; k=" + tt.algorithm + "; p=" + base64.StdEncoding.EncodeToString(tt.publicKey)
+			verifications, err := dkim.VerifyWithOptions(bytes.NewReader(message), &dkim.VerifyOptions{
+				LookupTXT: func(domain string) ([]string, error) {
+					if domain != "digest._domainkey.example.com" {
+						t.Errorf("Unexpected DKIM lookup for %s", domain)
+					}
+					return []string{record}, nil
+				},
+			})
+			if err != nil {
+				t.Fatalf("Failed to verify message: %v", err)
+			}
+			if len(verifications) != 1 {
+				t.Fatalf("Expected one DKIM signature, got %d", len(verifications))
+			}
+			if verifications[0].Err != nil {
+				t.Errorf("DKIM verification failed: %v", verifications[0].Err)
+			}
+			if verifications[0].Domain != "example.com" {
+				t.Errorf("Expected signing domain example.com, got %s", verifications[0].Domain)
+			}
+		})
+	}
+}
+
+func TestLoadDKIMKey(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate ECDSA key: %v", err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal ECDSA key: %v", err)
+	}
+	if _, err := loadDKIMKey(writeTestKey(t, "PRIVATE KEY", ecDER)); err == nil || !strings.Contains(err.Error(), "RSA or Ed25519") {
+		t.Errorf("Expected ECDSA keys to be rejected, got %v", err)
+	}
+
+	notPEM := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(notPEM, []byte("not a key"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := loadDKIMKey(notPEM); err == nil {
+		t.Error("Expected an error for a file without PEM data")
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	rsaDER, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal RSA key: %v", err)
+	}
+	key, err := loadDKIMKey(writeTestKey(t, "PRIVATE KEY", rsaDER))
+	if err != nil {
+		t.Fatalf("Failed to load PKCS#8 RSA key: %v", err)
+	}
+	if _, ok := key.Public().(*rsa.PublicKey); !ok {
+		t.Errorf("Expected an RSA key, got %T", key)
+	}
+}
@@ -1,45 +1,362 @@
 package email
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"miniflux-digest/internal/config"
 	"miniflux-digest/internal/app"
 	"miniflux-digest/internal/models"
 	"miniflux-digest/internal/templates"
+	"miniflux-digest/internal/unsubscribe"
 
 	"github.com/wneessen/go-mail"
 )
 
 
-type EmailServiceImpl struct{}
+// ConnectionIdleTimeout is how long the SMTP connection is kept open after
+// the last send, so the digests of one run share a single session.
+const ConnectionIdleTimeout = time.Minute
+
+type EmailServiceImpl struct {
+	transport   transport
+	maxSendRate int
+	idleTimeout time.Duration
+	msgOptions  []mail.MsgOption
+
+	unsubscribe  *unsubscribe.Signer
+	suppressions app.SuppressionList
+
+	mu        sync.Mutex
+	idleTimer *time.Timer
+	lastSent  time.Time
+}
 
 var _ app.EmailService = (*EmailServiceImpl)(nil)
 
-func (s *EmailServiceImpl) Send(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error {
-	message := mail.NewMsg()
-	client, err := mail.NewClient(
-		cfg.Smtp.Host,
-		mail.WithSMTPAuth(mail.SMTPAuthAutoDiscover),
-		mail.WithPort(cfg.Smtp.Port),
-		mail.WithUsername(cfg.Smtp.User),
-		mail.WithPassword(cfg.Smtp.Password))
+type EmailServiceOption func(*EmailServiceImpl)
+
+// WithIdleTimeout overrides ConnectionIdleTimeout.
+func WithIdleTimeout(timeout time.Duration) EmailServiceOption {
+	return func(s *EmailServiceImpl) {
+		s.idleTimeout = timeout
+	}
+}
+
+// WithSuppressionList skips recipients that unsubscribed from a category.
+func WithSuppressionList(l app.SuppressionList) EmailServiceOption {
+	return func(s *EmailServiceImpl) {
+		s.suppressions = l
+	}
+}
+
+// NewEmailService creates the transport configured by email.transport once.
+// An SMTP connection is dialed on the first send and reused until it has been
+// idle for ConnectionIdleTimeout or Close is called. Messages are DKIM signed
+// when smtp.dkim is configured and carry List-Unsubscribe headers when
+// digest.unsubscribe.secret is set.
+func NewEmailService(cfg *config.Config, opts ...EmailServiceOption) (*EmailServiceImpl, error) {
+	t, err := newTransport(cfg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &EmailServiceImpl{
+		transport:   t,
+		maxSendRate: cfg.Smtp.MaxSendRate,
+		idleTimeout: ConnectionIdleTimeout,
+	}
 
+	if cfg.Smtp.DKIM.Enabled() {
+		signer, err := newDKIMSigner(&cfg.Smtp.DKIM)
+		if err != nil {
+			return nil, err
+		}
+		s.msgOptions = append(s.msgOptions, mail.WithMiddleware(signer))
+	}
+
+	if cfg.Digest.Unsubscribe.Secret != "" {
+		s.unsubscribe = unsubscribe.NewSigner(cfg.Digest.Host, cfg.Digest.Unsubscribe.Secret)
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+func (s *EmailServiceImpl) Send(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error {
+	configured := len(cfg.Digest.Email.Recipients())
+	cfg, err := s.withoutSuppressed(cfg, data.Category.ID)
 	if err != nil {
 		return err
 	}
+	if configured > 0 && len(cfg.Digest.Email.Recipients()) == 0 {
+		log.Printf("All recipients unsubscribed from category '%s', not sending email", data.Category.Title)
+		return nil
+	}
 
-	if err := message.From(cfg.Digest.Email.From); err != nil {
+	messages, err := newMessages(cfg, file, data, s.msgOptions...)
+
+	if err != nil {
 		return err
 	}
 
-	if err := message.To(cfg.Digest.Email.To); err != nil {
+	if s.unsubscribe != nil {
+		for _, message := range messages {
+			if err := s.setUnsubscribeHeaders(message, data.Category.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.scheduleClose()
+
+	for i, message := range messages {
+		s.throttle()
+		err := s.transport.send(message)
+		s.lastSent = time.Now()
+		if err != nil {
+			if cfg.Digest.Email.Individual {
+				return &app.UndeliveredError{Recipients: cfg.Digest.Email.Recipients()[i:], Err: err}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close closes the SMTP connection if one is open.
+func (s *EmailServiceImpl) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+	}
+	return s.transport.close()
+}
+
+// withoutSuppressed returns cfg with the recipients that unsubscribed from
+// the category removed.
+func (s *EmailServiceImpl) withoutSuppressed(cfg *config.Config, categoryID int64) (*config.Config, error) {
+	if s.suppressions == nil {
+		return cfg, nil
+	}
+
+	filter := func(addresses []string) ([]string, error) {
+		var kept []string
+		for _, address := range addresses {
+			suppressed, err := s.suppressions.IsSuppressed(categoryID, address)
+			if err != nil {
+				return nil, err
+			}
+			if !suppressed {
+				kept = append(kept, address)
+			}
+		}
+		return kept, nil
+	}
+
+	filtered := *cfg
+	var err error
+	if filtered.Digest.Email.To, err = filter(cfg.Digest.Email.To); err != nil {
+		return nil, err
+	}
+	if filtered.Digest.Email.Cc, err = filter(cfg.Digest.Email.Cc); err != nil {
+		return nil, err
+	}
+	if filtered.Digest.Email.Bcc, err = filter(cfg.Digest.Email.Bcc); err != nil {
+		return nil, err
+	}
+	return &filtered, nil
+}
+
+// setUnsubscribeHeaders adds a one-click unsubscribe link (RFC 8058). The
+// link is personal, so messages shared by several recipients get none, which
+// config validation rules out by requiring digest.email.individual.
+func (s *EmailServiceImpl) setUnsubscribeHeaders(message *mail.Msg, categoryID int64) error {
+	recipients, err := message.GetRecipients()
+	if err != nil {
 		return err
 	}
+	if len(recipients) != 1 {
+		return nil
+	}
+
+	message.SetGenHeader(mail.HeaderListUnsubscribe, "<"+s.unsubscribe.URL(categoryID, recipients[0])+">")
+	message.SetGenHeader(mail.HeaderListUnsubscribePost, "List-Unsubscribe=One-Click")
+	return nil
+}
+
+// throttle waits until sending another message stays within smtp.max_send_rate.
+func (s *EmailServiceImpl) throttle() {
+	if s.maxSendRate <= 0 || s.lastSent.IsZero() {
+		return
+	}
+
+	interval := time.Minute / time.Duration(s.maxSendRate)
+	if wait := time.Until(s.lastSent.Add(interval)); wait > 0 {
+		time.Sleep(wait)
+	}
+}
+
+func (s *EmailServiceImpl) scheduleClose() {
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+	}
+
+	s.idleTimer = time.AfterFunc(s.idleTimeout, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if err := s.transport.close(); err != nil {
+			log.Printf("Error closing idle email transport: %v", err)
+		}
+	})
+}
+
+// clientOptions maps the smtp configuration to go-mail client options. Unset
+// values keep the previous behaviour of mandatory STARTTLS with
+// auto-discovered authentication, an unset port uses the default port of the
+// TLS mode.
+func clientOptions(smtp *config.ConfigSmtp) ([]mail.Option, error) {
+	var options []mail.Option
+
+	switch smtp.TLS {
+	case config.SmtpTLSNone:
+		options = append(options, mail.WithTLSPortPolicy(mail.NoTLS))
+	case config.SmtpTLSStartTLS:
+		options = append(options, mail.WithTLSPortPolicy(mail.TLSOpportunistic))
+	case config.SmtpTLSImplicit:
+		options = append(options, mail.WithSSLPort(false))
+	default:
+		options = append(options, mail.WithTLSPortPolicy(mail.TLSMandatory))
+	}
+
+	if smtp.Port != 0 {
+		options = append(options, mail.WithPort(smtp.Port))
+	}
+
+	if smtp.InsecureSkipVerify || smtp.CAFile != "" {
+		tlsConfig := &tls.Config{
+			ServerName:         smtp.Host,
+			InsecureSkipVerify: smtp.InsecureSkipVerify,
+			MinVersion:         tls.VersionTLS12,
+		}
+
+		if smtp.CAFile != "" {
+			ca, err := os.ReadFile(smtp.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read smtp.ca_file: %w", err)
+			}
+			tlsConfig.RootCAs = x509.NewCertPool()
+			if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("no certificates found in smtp.ca_file %s", smtp.CAFile)
+			}
+		}
+
+		options = append(options, mail.WithTLSConfig(tlsConfig))
+	}
+
+	var auth mail.SMTPAuthType
+	switch smtp.Auth {
+	case config.SmtpAuthNone:
+		return options, nil
+	case config.SmtpAuthPlain:
+		auth = mail.SMTPAuthPlain
+		if smtp.TLS == config.SmtpTLSNone {
+			auth = mail.SMTPAuthPlainNoEnc
+		}
+	case config.SmtpAuthLogin:
+		auth = mail.SMTPAuthLogin
+		if smtp.TLS == config.SmtpTLSNone {
+			auth = mail.SMTPAuthLoginNoEnc
+		}
+	case config.SmtpAuthCramMD5:
+		auth = mail.SMTPAuthCramMD5
+	default:
+		auth = mail.SMTPAuthAutoDiscover
+	}
+
+	return append(options,
+		mail.WithSMTPAuth(auth),
+		mail.WithUsername(smtp.User),
+		mail.WithPassword(smtp.Password),
+	), nil
+}
+
+// newMessages addresses the digest email to the configured recipients. With
+// individual sends, every recipient gets a copy addressed only to them, in the
+// order of Recipients.
+func newMessages(cfg *config.Config, file *os.File, data *models.HTMLTemplateData, opts ...mail.MsgOption) ([]*mail.Msg, error) {
+	recipients := cfg.Digest.Email.Recipients()
+	if len(recipients) == 0 {
+		return nil, errors.New("no email recipients configured")
+	}
+
+	if !cfg.Digest.Email.Individual {
+		message, err := newMessage(cfg, file, data, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if err := message.To(cfg.Digest.Email.To...); err != nil {
+			return nil, err
+		}
+		if len(cfg.Digest.Email.Cc) > 0 {
+			if err := message.Cc(cfg.Digest.Email.Cc...); err != nil {
+				return nil, err
+			}
+		}
+		if len(cfg.Digest.Email.Bcc) > 0 {
+			if err := message.Bcc(cfg.Digest.Email.Bcc...); err != nil {
+				return nil, err
+			}
+		}
+		return []*mail.Msg{message}, nil
+	}
+
+	messages := make([]*mail.Msg, 0, len(recipients))
+	for _, recipient := range recipients {
+		message, err := newMessage(cfg, file, data, opts...)
+		if err != nil {
+			return nil, err
+		}
+		if err := message.To(recipient); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// newMessage builds the digest email. The text body is always set, the
+// format decides whether the HTML body, the archive attachment or both are
+// added to it.
+func newMessage(cfg *config.Config, file *os.File, data *models.HTMLTemplateData, opts ...mail.MsgOption) (*mail.Msg, error) {
+	message := mail.NewMsg(opts...)
+
+	if err := message.From(cfg.Digest.Email.From); err != nil {
+		return nil, err
+	}
+
+	format := cfg.Digest.Email.Format
+	attach := format != config.EmailFormatInline
+	inline := format == config.EmailFormatInline || format == config.EmailFormatBoth
 
-	subject := fmt.Sprintf("[miniflux digest] %s", data.Category.Title)
 	filename := filepath.Base(file.Name())
 	dir := filepath.Base(filepath.Dir(file.Name()))
 	url := fmt.Sprintf("%s/%s/%s/%s", cfg.Digest.Host, "archive", dir, filename)
@@ -47,16 +364,66 @@ func (s *EmailServiceImpl) Send(cfg *config.Config, file *os.File, data *models.
 		HTMLTemplateData: *data,
 		URL:          url,
 		Summary:      data.Summary,
+		Attached:     attach,
 	}
 
+	subject, err := templates.RenderSubject(cfg.Digest.Email.Subject, textData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render email subject: %w", err)
+	}
 	message.Subject(subject)
-	message.AttachFile(file.Name(), mail.WithFileContentType("text/html"))
-
-	err = message.SetBodyTextTemplate(templates.EmailTemplate, textData)
 
+	body, err := templates.EmailBodyTemplate(cfg.Digest.Email.BodyTemplateFile)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if err := message.SetBodyTextTemplate(body, textData); err != nil {
+		return nil, err
+	}
+
+	if inline {
+		html, err := templates.RenderEmailHTML(textData)
+		if err != nil {
+			return nil, err
+		}
+		message.AddAlternativeString(mail.TypeTextHTML, html)
+
+		if err := embedFeedIcons(message, data.FeedIcons); err != nil {
+			return nil, err
+		}
+	}
+
+	if attach {
+		message.AttachFile(file.Name(), mail.WithFileContentType("text/html"))
 	}
 
-	return client.DialAndSend(message)
+	return message, nil
+}
+
+// embedFeedIcons embeds the feed icons as inline images referenced by their
+// Content-ID, since mail clients block data: URLs.
+func embedFeedIcons(message *mail.Msg, icons []*models.FeedIcon) error {
+	for _, icon := range icons {
+		contentType, payload, ok := strings.Cut(icon.Data, ";base64,")
+		if !ok {
+			log.Printf("Skipping feed icon %d with unsupported data encoding", icon.FeedID)
+			continue
+		}
+
+		image, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			log.Printf("Skipping feed icon %d: %v", icon.FeedID, err)
+			continue
+		}
+
+		err = message.EmbedReader(
+			templates.FeedIconCID(icon.FeedID),
+			bytes.NewReader(image),
+			mail.WithFileContentType(mail.ContentType(contentType)),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -1,13 +1,27 @@
 package email
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"miniflux-digest/internal/app"
 	"miniflux-digest/internal/config"
 	"miniflux-digest/internal/models"
 	"miniflux-digest/internal/templates"
 	"miniflux-digest/internal/testutil"
+	"miniflux-digest/internal/unsubscribe"
+	"net"
+	netmail "net/mail"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/wneessen/go-mail"
 )
 
 func TestSend(t *testing.T) {
@@ -20,7 +34,7 @@ func TestSend(t *testing.T) {
 		},
 		Digest: config.ConfigDigest{
 			Email: config.ConfigDigestEmail{
-				To:			 "to@example.com",
+				To:			 []string{"to@example.com"},
 				From:       "from@example.com",
 			},
 			Host:      "https://example.com",
@@ -62,7 +76,10 @@ func TestSend(t *testing.T) {
 	// In a real scenario, you would use a mock SMTP server.
 	// For this test, we are just checking if the function executes without error.
 	// The go-mail library does not make it easy to mock the SMTP client.
-	emailService := &EmailServiceImpl{}
+	emailService, err := NewEmailService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create email service: %v", err)
+	}
 	err = emailService.Send(cfg, file, &data)
 	if err != nil {
 		// We expect an error because we are not running a real SMTP server.
@@ -94,3 +111,609 @@ func TestTextTemplateData(t *testing.T) {
 		t.Errorf("Expected category title to be 'Test Category', got %s", textData.Category.Title)
 	}
 }
+
+func TestNewMessageFormat(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "test-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	if _, err := tmpFile.WriteString("<html><body><h1>Test</h1></body></html>"); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	defer func() {
+		if err := tmpFile.Close(); err != nil {
+			t.Errorf("Failed to close temp file: %v", err)
+		}
+	}()
+
+	data := models.HTMLTemplateData{
+		Category:  testutil.NewMockCategory(),
+		Entries:   testutil.NewMockEntries(),
+		FeedIcons: testutil.NewMockFeedIcons(),
+	}
+
+	tests := []struct {
+		format         config.EmailFormat
+		wantHTML       bool
+		wantAttachment bool
+	}{
+		{format: "", wantHTML: false, wantAttachment: true},
+		{format: config.EmailFormatAttachment, wantHTML: false, wantAttachment: true},
+		{format: config.EmailFormatInline, wantHTML: true, wantAttachment: false},
+		{format: config.EmailFormatBoth, wantHTML: true, wantAttachment: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			cfg := &config.Config{
+				Digest: config.ConfigDigest{
+					Email: config.ConfigDigestEmail{
+						To:     []string{"to@example.com"},
+						From:   "from@example.com",
+						Format: tt.format,
+					},
+					Host: "https://example.com",
+				},
+			}
+
+			message, err := newMessage(cfg, tmpFile, &data)
+			if err != nil {
+				t.Fatalf("newMessage failed: %v", err)
+			}
+
+			var buf bytes.Buffer
+			if _, err := message.WriteTo(&buf); err != nil {
+				t.Fatalf("Failed to write message: %v", err)
+			}
+			raw := buf.String()
+
+			if !strings.Contains(raw, "text/plain") {
+				t.Error("Expected a plain text part")
+			}
+			if got := strings.Contains(raw, "multipart/alternative"); got != tt.wantHTML {
+				t.Errorf("Expected multipart/alternative %v, got %v", tt.wantHTML, got)
+			}
+			if got := strings.Contains(raw, "Content-Disposition: attachment"); got != tt.wantAttachment {
+				t.Errorf("Expected attachment %v, got %v", tt.wantAttachment, got)
+			}
+			if got := strings.Contains(raw, "Content-Id: <feed-icon-"); got != tt.wantHTML {
+				t.Errorf("Expected embedded feed icons %v, got %v", tt.wantHTML, got)
+			}
+		})
+	}
+}
+
+func TestNewMessageTemplates(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "test-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = tmpFile.Close() }()
+
+	body := filepath.Join(t.TempDir(), "body.gotxt")
+	if err := os.WriteFile(body, []byte("Custom body for {{ .Category.Title }}"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	if err := templates.Load("", []string{body}, nil); err != nil {
+		t.Fatalf("Failed to load templates: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := templates.Load("", nil, nil); err != nil {
+			t.Fatalf("Failed to restore embedded templates: %v", err)
+		}
+	})
+
+	cfg := &config.Config{
+		Digest: config.ConfigDigest{
+			Email: config.ConfigDigestEmail{
+				To:               []string{"to@example.com"},
+				From:             "from@example.com",
+				Subject:          "{{ .EntryCount }} new in {{ .Category.Title }}",
+				BodyTemplateFile: body,
+			},
+			Host: "https://example.com",
+		},
+	}
+	data := models.HTMLTemplateData{Category: testutil.NewMockCategory(), Entries: testutil.NewMockEntries()}
+
+	message, err := newMessage(cfg, tmpFile, &data)
+	if err != nil {
+		t.Fatalf("newMessage failed: %v", err)
+	}
+
+	want := fmt.Sprintf("%d new in Test Category", len(*data.Entries))
+	if got := message.GetGenHeader(mail.HeaderSubject); len(got) != 1 || got[0] != want {
+		t.Errorf("Expected subject %q, got %v", want, got)
+	}
+
+	var buf bytes.Buffer
+	if _, err := message.WriteTo(&buf); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	if !strings.Contains(buf.String(), "Custom body for Test Category") {
+		t.Errorf("Expected the external body template to be used, got %s", buf.String())
+	}
+}
+
+func TestNewMessagesRecipients(t *testing.T) {
+	tmpFile, err := os.CreateTemp(t.TempDir(), "test-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() {
+		if err := tmpFile.Close(); err != nil {
+			t.Errorf("Failed to close temp file: %v", err)
+		}
+	}()
+
+	data := models.HTMLTemplateData{
+		Category: testutil.NewMockCategory(),
+		Entries:  testutil.NewMockEntries(),
+	}
+	email := config.ConfigDigestEmail{
+		To:   []string{"one@example.com", "two@example.com"},
+		Cc:   []string{"three@example.com"},
+		Bcc:  []string{"four@example.com", "one@example.com"},
+		From: "from@example.com",
+	}
+
+	t.Run("shared", func(t *testing.T) {
+		cfg := &config.Config{Digest: config.ConfigDigest{Email: email}}
+		messages, err := newMessages(cfg, tmpFile, &data)
+		if err != nil {
+			t.Fatalf("newMessages failed: %v", err)
+		}
+		if len(messages) != 1 {
+			t.Fatalf("Expected a single message, got %d", len(messages))
+		}
+
+		recipients, err := messages[0].GetRecipients()
+		if err != nil {
+			t.Fatalf("Failed to get recipients: %v", err)
+		}
+		if len(recipients) != 5 {
+			t.Errorf("Expected to, cc and bcc recipients, got %v", recipients)
+		}
+		if cc := messages[0].GetCcString(); len(cc) != 1 || cc[0] != "<three@example.com>" {
+			t.Errorf("Expected cc header, got %v", cc)
+		}
+	})
+
+	t.Run("individual", func(t *testing.T) {
+		individual := email
+		individual.Individual = true
+		cfg := &config.Config{Digest: config.ConfigDigest{Email: individual}}
+		messages, err := newMessages(cfg, tmpFile, &data)
+		if err != nil {
+			t.Fatalf("newMessages failed: %v", err)
+		}
+		if len(messages) != 4 {
+			t.Fatalf("Expected one message per unique recipient, got %d", len(messages))
+		}
+
+		for _, message := range messages {
+			recipients, err := message.GetRecipients()
+			if err != nil {
+				t.Fatalf("Failed to get recipients: %v", err)
+			}
+			if len(recipients) != 1 || len(message.GetCcString()) != 0 {
+				t.Errorf("Expected each message to be addressed to one recipient, got %v", recipients)
+			}
+		}
+	})
+
+	t.Run("no recipients", func(t *testing.T) {
+		cfg := &config.Config{Digest: config.ConfigDigest{Email: config.ConfigDigestEmail{From: "from@example.com"}}}
+		if _, err := newMessages(cfg, tmpFile, &data); err == nil {
+			t.Error("Expected error without recipients")
+		}
+	})
+}
+
+func TestClientOptions(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(caFile, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("Failed to write CA file: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		smtp    config.ConfigSmtp
+		wantErr bool
+	}{
+		{name: "defaults", smtp: config.ConfigSmtp{}},
+		{name: "relay without tls or auth", smtp: config.ConfigSmtp{Port: 25, TLS: config.SmtpTLSNone, Auth: config.SmtpAuthNone}},
+		{name: "plain auth without tls", smtp: config.ConfigSmtp{Port: 25, TLS: config.SmtpTLSNone, Auth: config.SmtpAuthPlain, User: "user"}},
+		{name: "opportunistic starttls with login", smtp: config.ConfigSmtp{Port: 587, TLS: config.SmtpTLSStartTLS, Auth: config.SmtpAuthLogin, User: "user"}},
+		{name: "implicit tls with crammd5", smtp: config.ConfigSmtp{Port: 465, TLS: config.SmtpTLSImplicit, Auth: config.SmtpAuthCramMD5, User: "user"}},
+		{name: "insecure skip verify", smtp: config.ConfigSmtp{Port: 465, TLS: config.SmtpTLSImplicit, InsecureSkipVerify: true}},
+		{name: "invalid ca file", smtp: config.ConfigSmtp{Port: 587, CAFile: caFile}, wantErr: true},
+		{name: "missing ca file", smtp: config.ConfigSmtp{Port: 587, CAFile: filepath.Join(t.TempDir(), "missing.pem")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.smtp.Host = "localhost"
+			options, err := clientOptions(&tt.smtp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("clientOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if _, err := mail.NewClient(tt.smtp.Host, options...); err != nil {
+				t.Errorf("Expected options to create a client, got %v", err)
+			}
+		})
+	}
+}
+
+type testSMTPServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    int
+	messages int
+}
+
+// newTestSMTPServer starts a minimal SMTP server that accepts every message
+// without TLS or authentication.
+func newTestSMTPServer(t *testing.T) *testSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &testSMTPServer{listener: listener}
+	t.Cleanup(func() {
+		if err := listener.Close(); err != nil {
+			t.Logf("Failed to close listener: %v", err)
+		}
+	})
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			server.mu.Lock()
+			server.conns++
+			server.mu.Unlock()
+			go server.handle(conn)
+		}
+	}()
+	return server
+}
+
+func (s *testSMTPServer) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = fmt.Fprintf(conn, "%s\r\n", line) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case command == "DATA":
+			reply("354 end data with <CR><LF>.<CR><LF>")
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+			}
+			s.mu.Lock()
+			s.messages++
+			s.mu.Unlock()
+			reply("250 ok")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+func (s *testSMTPServer) counts() (int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns, s.messages
+}
+
+func (s *testSMTPServer) config(t *testing.T) config.ConfigSmtp {
+	t.Helper()
+	addr := s.listener.Addr().(*net.TCPAddr)
+	return config.ConfigSmtp{
+		Host: addr.IP.String(),
+		Port: addr.Port,
+		TLS:  config.SmtpTLSNone,
+		Auth: config.SmtpAuthNone,
+	}
+}
+
+func TestEmailServiceReusesConnection(t *testing.T) {
+	server := newTestSMTPServer(t)
+	cfg := &config.Config{
+		Smtp: server.config(t),
+		Digest: config.ConfigDigest{
+			Email: config.ConfigDigestEmail{
+				To:   []string{"to@example.com"},
+				From: "from@example.com",
+			},
+		},
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "test-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	data := models.HTMLTemplateData{Category: testutil.NewMockCategory(), Entries: testutil.NewMockEntries()}
+
+	emailService, err := NewEmailService(cfg, WithIdleTimeout(time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to create email service: %v", err)
+	}
+
+	for range 3 {
+		if err := emailService.Send(cfg, file, &data); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+	if conns, messages := server.counts(); conns != 1 || messages != 3 {
+		t.Errorf("Expected 3 messages over 1 connection, got %d messages over %d connections", messages, conns)
+	}
+
+	if err := emailService.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := emailService.Send(cfg, file, &data); err != nil {
+		t.Fatalf("Send after Close failed: %v", err)
+	}
+	if conns, messages := server.counts(); conns != 2 || messages != 4 {
+		t.Errorf("Expected a new connection after Close, got %d messages over %d connections", messages, conns)
+	}
+	if err := emailService.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+}
+
+func TestEmailServiceMaxSendRate(t *testing.T) {
+	server := newTestSMTPServer(t)
+	smtp := server.config(t)
+	smtp.MaxSendRate = 600
+	cfg := &config.Config{
+		Smtp: smtp,
+		Digest: config.ConfigDigest{
+			Email: config.ConfigDigestEmail{
+				To:         []string{"one@example.com", "two@example.com", "three@example.com"},
+				From:       "from@example.com",
+				Individual: true,
+			},
+		},
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "test-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	data := models.HTMLTemplateData{Category: testutil.NewMockCategory(), Entries: testutil.NewMockEntries()}
+
+	emailService, err := NewEmailService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create email service: %v", err)
+	}
+	defer func() { _ = emailService.Close() }()
+
+	start := time.Now()
+	if err := emailService.Send(cfg, file, &data); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected 3 messages at 600/min to take at least 200ms, took %v", elapsed)
+	}
+	if _, messages := server.counts(); messages != 3 {
+		t.Errorf("Expected 3 messages, got %d", messages)
+	}
+}
+
+func TestEmailServiceIdleTimeout(t *testing.T) {
+	server := newTestSMTPServer(t)
+	cfg := &config.Config{
+		Smtp: server.config(t),
+		Digest: config.ConfigDigest{
+			Email: config.ConfigDigestEmail{To: []string{"to@example.com"}, From: "from@example.com"},
+		},
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "test-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	data := models.HTMLTemplateData{Category: testutil.NewMockCategory(), Entries: testutil.NewMockEntries()}
+
+	emailService, err := NewEmailService(cfg, WithIdleTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create email service: %v", err)
+	}
+	defer func() { _ = emailService.Close() }()
+
+	if err := emailService.Send(cfg, file, &data); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := emailService.Send(cfg, file, &data); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if conns, _ := server.counts(); conns != 2 {
+		t.Errorf("Expected idle connection to be closed and redialed, got %d connections", conns)
+	}
+}
+
+func TestEmailServiceUnsubscribe(t *testing.T) {
+	maildir := filepath.Join(t.TempDir(), "Maildir")
+	cfg := newTransportTestConfig(config.ConfigEmail{
+		Transport: config.EmailTransportMaildir,
+		Maildir:   config.ConfigMaildir{Path: maildir},
+	})
+	cfg.Digest.Email.To = []string{"to@example.com", "gone@example.com"}
+	cfg.Digest.Email.Individual = true
+	cfg.Digest.Unsubscribe.Secret = "secret"
+
+	suppressions, err := unsubscribe.NewFileSuppressionList(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create suppression list: %v", err)
+	}
+	if err := suppressions.Suppress(testutil.NewMockCategory().ID, "gone@example.com"); err != nil {
+		t.Fatalf("Suppress failed: %v", err)
+	}
+
+	sendTestDigest(t, cfg, WithSuppressionList(suppressions))
+
+	delivered, err := os.ReadDir(filepath.Join(maildir, "new"))
+	if err != nil {
+		t.Fatalf("Failed to read maildir: %v", err)
+	}
+	if len(delivered) != 2 {
+		t.Fatalf("Expected messages for to@ and bcc@ only, got %d", len(delivered))
+	}
+
+	signer := unsubscribe.NewSigner(cfg.Digest.Host, cfg.Digest.Unsubscribe.Secret)
+	for _, entry := range delivered {
+		message, err := os.ReadFile(filepath.Join(maildir, "new", entry.Name()))
+		if err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		parsed, err := netmail.ReadMessage(bytes.NewReader(message))
+		if err != nil {
+			t.Fatalf("Failed to parse message: %v", err)
+		}
+
+		recipient := strings.Trim(parsed.Header.Get("To"), "<>")
+		if recipient == "gone@example.com" {
+			t.Errorf("Expected no message for the unsubscribed recipient")
+		}
+		if got := parsed.Header.Get("List-Unsubscribe-Post"); got != "List-Unsubscribe=One-Click" {
+			t.Errorf("Expected a one-click List-Unsubscribe-Post header, got %q", got)
+		}
+		want := "<" + signer.URL(testutil.NewMockCategory().ID, recipient) + ">"
+		if got := parsed.Header.Get("List-Unsubscribe"); got != want {
+			t.Errorf("Expected List-Unsubscribe %q, got %q", want, got)
+		}
+	}
+}
+
+func TestEmailServiceAllUnsubscribed(t *testing.T) {
+	maildir := filepath.Join(t.TempDir(), "Maildir")
+	cfg := newTransportTestConfig(config.ConfigEmail{
+		Transport: config.EmailTransportMaildir,
+		Maildir:   config.ConfigMaildir{Path: maildir},
+	})
+
+	suppressions, err := unsubscribe.NewFileSuppressionList(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create suppression list: %v", err)
+	}
+	for _, address := range cfg.Digest.Email.Recipients() {
+		if err := suppressions.Suppress(testutil.NewMockCategory().ID, address); err != nil {
+			t.Fatalf("Suppress failed: %v", err)
+		}
+	}
+
+	sendTestDigest(t, cfg, WithSuppressionList(suppressions))
+
+	if delivered, _ := os.ReadDir(filepath.Join(maildir, "new")); len(delivered) != 0 {
+		t.Errorf("Expected no email when every recipient unsubscribed, got %d", len(delivered))
+	}
+}
+
+func TestEmailServiceNoRecipients(t *testing.T) {
+	cfg := &config.Config{
+		Digest: config.ConfigDigest{
+			Email: config.ConfigDigestEmail{From: "from@example.com"},
+		},
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "test-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	data := models.HTMLTemplateData{Category: testutil.NewMockCategory(), Entries: testutil.NewMockEntries()}
+
+	suppressions, err := unsubscribe.NewFileSuppressionList(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create suppression list: %v", err)
+	}
+	transport := &failingTransport{failAt: -1}
+	emailService := &EmailServiceImpl{transport: transport, suppressions: suppressions, idleTimeout: time.Hour}
+	defer func() { _ = emailService.Close() }()
+
+	if err := emailService.Send(cfg, file, &data); err == nil {
+		t.Error("Expected an error when no recipient is configured")
+	}
+	if transport.sent != 0 {
+		t.Errorf("Expected no email to be sent, got %d", transport.sent)
+	}
+}
+
+type failingTransport struct {
+	sent   int
+	failAt int
+}
+
+func (f *failingTransport) send(message *mail.Msg) error {
+	if f.sent == f.failAt {
+		return errors.New("smtp down")
+	}
+	f.sent++
+	return nil
+}
+
+func (f *failingTransport) close() error {
+	return nil
+}
+
+func TestEmailServiceUndeliveredRecipients(t *testing.T) {
+	cfg := &config.Config{
+		Digest: config.ConfigDigest{
+			Email: config.ConfigDigestEmail{
+				To:         []string{"one@example.com", "two@example.com"},
+				Bcc:        []string{"three@example.com"},
+				From:       "from@example.com",
+				Individual: true,
+			},
+		},
+	}
+
+	file, err := os.CreateTemp(t.TempDir(), "test-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+	data := models.HTMLTemplateData{Category: testutil.NewMockCategory(), Entries: testutil.NewMockEntries()}
+
+	emailService := &EmailServiceImpl{transport: &failingTransport{failAt: 1}, idleTimeout: time.Hour}
+	defer func() { _ = emailService.Close() }()
+
+	err = emailService.Send(cfg, file, &data)
+	var undelivered *app.UndeliveredError
+	if !errors.As(err, &undelivered) {
+		t.Fatalf("Expected an UndeliveredError, got %v", err)
+	}
+	if want := []string{"two@example.com", "three@example.com"}; !slices.Equal(undelivered.Recipients, want) {
+		t.Errorf("Expected undelivered recipients %v, got %v", want, undelivered.Recipients)
+	}
+}
@@ -0,0 +1,189 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/wneessen/go-mail"
+
+	"miniflux-digest/internal/config"
+)
+
+// SendmailTimeout limits how long the sendmail binary may take per message.
+const SendmailTimeout = 30 * time.Second
+
+// transport delivers one message at a time. close releases any open
+// connection, a later send opens a new one.
+type transport interface {
+	send(message *mail.Msg) error
+	close() error
+}
+
+func newTransport(cfg *config.Config) (transport, error) {
+	switch cfg.Email.Transport {
+	case config.EmailTransportSendmail:
+		return &sendmailTransport{path: cfg.Email.Sendmail.Path, args: cfg.Email.Sendmail.Args}, nil
+	case config.EmailTransportMaildir:
+		return newMaildirTransport(cfg.Email.Maildir.Path)
+	default:
+		return newSMTPTransport(&cfg.Smtp)
+	}
+}
+
+type smtpTransport struct {
+	client    *mail.Client
+	connected bool
+}
+
+func newSMTPTransport(smtp *config.ConfigSmtp) (*smtpTransport, error) {
+	options, err := clientOptions(smtp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := mail.NewClient(smtp.Host, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &smtpTransport{client: client}, nil
+}
+
+// send delivers a message on the open connection, dialing a new one when
+// there is none or the server has dropped it.
+func (t *smtpTransport) send(message *mail.Msg) error {
+	if !t.connected {
+		if err := t.client.DialWithContext(context.Background()); err != nil {
+			return err
+		}
+		t.connected = true
+	}
+
+	err := t.client.Send(message)
+
+	var sendErr *mail.SendError
+	if errors.As(err, &sendErr) && sendErr.Reason == mail.ErrConnCheck {
+		log.Printf("SMTP connection lost, reconnecting: %v", err)
+		if err := t.close(); err != nil {
+			log.Printf("Error closing SMTP connection: %v", err)
+		}
+		if err := t.client.DialWithContext(context.Background()); err != nil {
+			return err
+		}
+		t.connected = true
+		err = t.client.Send(message)
+	}
+
+	return err
+}
+
+func (t *smtpTransport) close() error {
+	if !t.connected {
+		return nil
+	}
+
+	t.connected = false
+	return t.client.Close()
+}
+
+// sendmailTransport pipes every message to a local sendmail compatible
+// binary. Recipients are passed as arguments rather than read from the
+// headers, so bcc recipients are delivered too.
+type sendmailTransport struct {
+	path string
+	args []string
+}
+
+func (t *sendmailTransport) send(message *mail.Msg) error {
+	from, err := message.GetSender(false)
+	if err != nil {
+		return err
+	}
+
+	recipients, err := message.GetRecipients()
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	if _, err := message.WriteTo(&body); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), SendmailTimeout)
+	defer cancel()
+
+	args := append(append([]string{}, t.args...), "-oi", "-f", from, "--")
+	cmd := exec.CommandContext(ctx, t.path, append(args, recipients...)...)
+	cmd.Stdin = &body
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sendmail failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func (t *sendmailTransport) close() error {
+	return nil
+}
+
+// maildirTransport delivers every message as a file into the new directory
+// of a Maildir, writing it to tmp first as the Maildir format requires.
+type maildirTransport struct {
+	dir      string
+	hostname string
+	count    atomic.Uint64
+}
+
+func newMaildirTransport(dir string) (*maildirTransport, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create maildir: %w", err)
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	hostname = strings.NewReplacer("/", "\\057", ":", "\\072").Replace(hostname)
+
+	return &maildirTransport{dir: dir, hostname: hostname}, nil
+}
+
+func (t *maildirTransport) send(message *mail.Msg) error {
+	now := time.Now()
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s", now.Unix(), now.Nanosecond()/1000, os.Getpid(), t.count.Add(1), t.hostname)
+	tmpPath := filepath.Join(t.dir, "tmp", name)
+
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := message.WriteTo(file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, filepath.Join(t.dir, "new", name))
+}
+
+func (t *maildirTransport) close() error {
+	return nil
+}
@@ -0,0 +1,130 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"miniflux-digest/internal/config"
+	"miniflux-digest/internal/models"
+	"miniflux-digest/internal/testutil"
+)
+
+func newTransportTestConfig(transport config.ConfigEmail) *config.Config {
+	return &config.Config{
+		Email: transport,
+		Digest: config.ConfigDigest{
+			Email: config.ConfigDigestEmail{
+				To:   []string{"to@example.com"},
+				Bcc:  []string{"bcc@example.com"},
+				From: "from@example.com",
+			},
+			Host: "https://example.com",
+		},
+	}
+}
+
+func sendTestDigest(t *testing.T, cfg *config.Config, opts ...EmailServiceOption) {
+	t.Helper()
+	file, err := os.CreateTemp(t.TempDir(), "test-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	emailService, err := NewEmailService(cfg, opts...)
+	if err != nil {
+		t.Fatalf("Failed to create email service: %v", err)
+	}
+	defer func() { _ = emailService.Close() }()
+
+	data := models.HTMLTemplateData{Category: testutil.NewMockCategory(), Entries: testutil.NewMockEntries()}
+	if err := emailService.Send(cfg, file, &data); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+}
+
+func TestMaildirTransport(t *testing.T) {
+	maildir := filepath.Join(t.TempDir(), "Maildir")
+	cfg := newTransportTestConfig(config.ConfigEmail{
+		Transport: config.EmailTransportMaildir,
+		Maildir:   config.ConfigMaildir{Path: maildir},
+	})
+	cfg.Digest.Email.Individual = true
+
+	sendTestDigest(t, cfg)
+
+	delivered, err := os.ReadDir(filepath.Join(maildir, "new"))
+	if err != nil {
+		t.Fatalf("Failed to read maildir: %v", err)
+	}
+	if len(delivered) != 2 {
+		t.Fatalf("Expected one message per recipient in new, got %d", len(delivered))
+	}
+	if pending, _ := os.ReadDir(filepath.Join(maildir, "tmp")); len(pending) != 0 {
+		t.Errorf("Expected tmp to be empty after delivery, got %d files", len(pending))
+	}
+
+	message, err := os.ReadFile(filepath.Join(maildir, "new", delivered[0].Name()))
+	if err != nil {
+		t.Fatalf("Failed to read message: %v", err)
+	}
+	if !strings.Contains(string(message), "Subject: [miniflux digest] Test Category") {
+		t.Errorf("Expected an RFC 5322 message with the digest subject, got %s", message)
+	}
+}
+
+func TestSendmailTransport(t *testing.T) {
+	dir := t.TempDir()
+	sendmail := filepath.Join(dir, "sendmail")
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "args") + "\ncat > " + filepath.Join(dir, "message") + "\n"
+	if err := os.WriteFile(sendmail, []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake sendmail: %v", err)
+	}
+
+	cfg := newTransportTestConfig(config.ConfigEmail{
+		Transport: config.EmailTransportSendmail,
+		Sendmail:  config.ConfigSendmail{Path: sendmail, Args: []string{"-X", "/dev/null"}},
+	})
+
+	sendTestDigest(t, cfg)
+
+	args, err := os.ReadFile(filepath.Join(dir, "args"))
+	if err != nil {
+		t.Fatalf("Expected sendmail to be called: %v", err)
+	}
+	if want := "-X /dev/null -oi -f from@example.com -- to@example.com bcc@example.com"; strings.TrimSpace(string(args)) != want {
+		t.Errorf("Expected sendmail arguments %q, got %q", want, strings.TrimSpace(string(args)))
+	}
+
+	message, err := os.ReadFile(filepath.Join(dir, "message"))
+	if err != nil {
+		t.Fatalf("Failed to read piped message: %v", err)
+	}
+	if !strings.Contains(string(message), "To: <to@example.com>") || strings.Contains(string(message), "bcc@example.com") {
+		t.Errorf("Expected message headers without bcc, got %s", message)
+	}
+}
+
+func TestSendmailTransportFailure(t *testing.T) {
+	cfg := newTransportTestConfig(config.ConfigEmail{
+		Transport: config.EmailTransportSendmail,
+		Sendmail:  config.ConfigSendmail{Path: filepath.Join(t.TempDir(), "missing")},
+	})
+
+	emailService, err := NewEmailService(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create email service: %v", err)
+	}
+	file, err := os.CreateTemp(t.TempDir(), "test-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	data := models.HTMLTemplateData{Category: testutil.NewMockCategory(), Entries: testutil.NewMockEntries()}
+	if err := emailService.Send(cfg, file, &data); err == nil {
+		t.Error("Expected error for a missing sendmail binary")
+	}
+}
@@ -0,0 +1,224 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"miniflux-digest/internal/utils"
+)
+
+const (
+	DefaultCacheTTL     = 24 * time.Hour
+	DefaultCacheMaxSize = 50 << 20
+)
+
+// CachedService wraps an LLMService and keeps its responses on disk, so a
+// retried digest or a preview of the same entries neither pays for the same
+// prompt twice nor gets a different answer.
+type CachedService struct {
+	service  LLMService
+	dir      string
+	identity string
+	ttl      time.Duration
+	maxSize  int64
+	mu       sync.Mutex
+	size     int64
+}
+
+var _ LLMService = (*CachedService)(nil)
+
+type CacheOption func(*CachedService)
+
+type noCacheKey struct{}
+
+// WithoutCache has a CachedService skip its cache for the requests made with
+// the returned context, for callers that cache the results themselves.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// WithCacheTTL overrides DefaultCacheTTL.
+func WithCacheTTL(ttl time.Duration) CacheOption {
+	return func(s *CachedService) {
+		s.ttl = ttl
+	}
+}
+
+// WithCacheMaxSize overrides DefaultCacheMaxSize in bytes, 0 disables the
+// limit. The oldest responses are dropped first.
+func WithCacheMaxSize(size int64) CacheOption {
+	return func(s *CachedService) {
+		s.maxSize = size
+	}
+}
+
+// NewCachedService wraps service, keying responses by its CacheKey when it
+// has one, so a different provider, server, model or option is a cache miss.
+// Expired responses are removed once when the cache is opened and the cache
+// is only pruned again when it grows beyond its size limit.
+func NewCachedService(service LLMService, dir string, opts ...CacheOption) (*CachedService, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create LLM cache directory: %w", err)
+	}
+
+	s := &CachedService{service: service, dir: dir, ttl: DefaultCacheTTL, maxSize: DefaultCacheMaxSize}
+	if k, ok := service.(interface{ CacheKey() string }); ok {
+		s.identity = k.CacheKey()
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.prune(); err != nil {
+		return nil, fmt.Errorf("failed to prune LLM cache: %w", err)
+	}
+	return s, nil
+}
+
+func (s *CachedService) GenerateContent(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	if skip, _ := ctx.Value(noCacheKey{}).(bool); skip {
+		return s.service.GenerateContent(ctx, prompt, schema)
+	}
+
+	key := s.key(prompt, schema)
+	if response, ok := s.get(key); ok {
+		return response, nil
+	}
+
+	response, err := s.service.GenerateContent(ctx, prompt, schema)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.put(key, response); err != nil {
+		log.Printf("Error caching LLM response: %v\n", err)
+	}
+	return response, nil
+}
+
+// key hashes the identity of the wrapped service, the schema and the prompt.
+// Prompts hold their template and the IDs and content of the entries, so a
+// changed template or entry is a cache miss.
+func (s *CachedService) key(prompt string, schema *Schema) string {
+	schemaJSON, _ := json.Marshal(schema)
+	hash := sha256.Sum256(fmt.Appendf(nil, "%s\x00%s\x00%s", s.identity, schemaJSON, prompt))
+	return hex.EncodeToString(hash[:])
+}
+
+func (s *CachedService) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+func (s *CachedService) get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.path(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Error reading cached LLM response: %v\n", err)
+		}
+		return "", false
+	}
+	if time.Since(info.ModTime()) > s.ttl {
+		s.remove(info)
+		return "", false
+	}
+
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		log.Printf("Error reading cached LLM response: %v\n", err)
+		return "", false
+	}
+	return string(data), true
+}
+
+// put writes the response atomically, so a crash never leaves a partially
+// written response behind, then prunes the cache when it grew beyond maxSize.
+func (s *CachedService) put(key, response string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var previous int64
+	if info, err := os.Stat(s.path(key)); err == nil {
+		previous = info.Size()
+	}
+	if err := utils.WriteFileAtomic(s.path(key), []byte(response)); err != nil {
+		return err
+	}
+	s.size += int64(len(response)) - previous
+
+	if s.maxSize > 0 && s.size > s.maxSize {
+		return s.prune()
+	}
+	return nil
+}
+
+// prune removes expired responses and then the oldest ones until the cache
+// fits in maxSize, and recounts its size.
+func (s *CachedService) prune() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	var files []fs.FileInfo
+	s.size = 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) > s.ttl {
+			if err := os.Remove(filepath.Join(s.dir, info.Name())); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				log.Printf("Error removing cached LLM response: %v\n", err)
+			}
+			continue
+		}
+		files = append(files, info)
+		s.size += info.Size()
+	}
+
+	if s.maxSize <= 0 {
+		return nil
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	for _, info := range files {
+		if s.size <= s.maxSize {
+			break
+		}
+		s.remove(info)
+	}
+	return nil
+}
+
+// remove deletes a cached response and takes it off the size of the cache.
+func (s *CachedService) remove(info fs.FileInfo) {
+	if err := os.Remove(filepath.Join(s.dir, info.Name())); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Error removing cached LLM response: %v\n", err)
+		}
+		return
+	}
+	s.size -= info.Size()
+}
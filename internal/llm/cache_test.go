@@ -0,0 +1,245 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type countingLLMService struct {
+	calls    int
+	err      error
+	cacheKey string
+}
+
+func (m *countingLLMService) GenerateContent(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	m.calls++
+	if m.err != nil {
+		return "", m.err
+	}
+	return fmt.Sprintf(`{"response":%d}`, m.calls), nil
+}
+
+func (m *countingLLMService) CacheKey() string {
+	if m.cacheKey == "" {
+		return "test-model"
+	}
+	return m.cacheKey
+}
+
+func cacheFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatalf("Failed to list cache files: %v", err)
+	}
+	return files
+}
+
+func TestCachedService(t *testing.T) {
+	dir := t.TempDir()
+	mock := &countingLLMService{}
+	service, err := NewCachedService(mock, dir)
+	if err != nil {
+		t.Fatalf("NewCachedService() error = %v", err)
+	}
+	if service.identity != "test-model" {
+		t.Errorf("Expected the cache key of the wrapped service, got %q", service.identity)
+	}
+
+	schema := &Schema{Type: TypeObject}
+	first, err := service.GenerateContent(context.Background(), "prompt", schema)
+	if err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+
+	// A new service reuses the responses on disk.
+	service, err = NewCachedService(mock, dir)
+	if err != nil {
+		t.Fatalf("NewCachedService() error = %v", err)
+	}
+	second, err := service.GenerateContent(context.Background(), "prompt", schema)
+	if err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+	if first != second || mock.calls != 1 {
+		t.Errorf("Expected a cached response, got %q then %q after %d calls", first, second, mock.calls)
+	}
+
+	if _, err := service.GenerateContent(context.Background(), "other prompt", schema); err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+	if _, err := service.GenerateContent(context.Background(), "prompt", &Schema{Type: TypeArray}); err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+	if mock.calls != 3 {
+		t.Errorf("Expected a different prompt or schema to miss the cache, got %d calls", mock.calls)
+	}
+	if files := cacheFiles(t, dir); len(files) != 3 {
+		t.Errorf("Expected 3 cached responses, got %d", len(files))
+	}
+}
+
+func TestCachedService_Errors(t *testing.T) {
+	dir := t.TempDir()
+	mock := &countingLLMService{err: errors.New("LLM error")}
+	service, err := NewCachedService(mock, dir)
+	if err != nil {
+		t.Fatalf("NewCachedService() error = %v", err)
+	}
+
+	if _, err := service.GenerateContent(context.Background(), "prompt", nil); err == nil {
+		t.Fatal("Expected the error of the wrapped service")
+	}
+	if files := cacheFiles(t, dir); len(files) != 0 {
+		t.Errorf("Expected errors not to be cached, got %d files", len(files))
+	}
+}
+
+func TestCachedService_TTL(t *testing.T) {
+	dir := t.TempDir()
+	mock := &countingLLMService{}
+	service, err := NewCachedService(mock, dir, WithCacheTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("NewCachedService() error = %v", err)
+	}
+
+	if _, err := service.GenerateContent(context.Background(), "prompt", nil); err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+	expired := time.Now().Add(-2 * time.Hour)
+	for _, file := range cacheFiles(t, dir) {
+		if err := os.Chtimes(file, expired, expired); err != nil {
+			t.Fatalf("Failed to age cache file: %v", err)
+		}
+	}
+
+	if _, err := service.GenerateContent(context.Background(), "prompt", nil); err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+	if mock.calls != 2 {
+		t.Errorf("Expected an expired response to be generated again, got %d calls", mock.calls)
+	}
+}
+
+func TestCachedService_MaxSize(t *testing.T) {
+	dir := t.TempDir()
+	mock := &countingLLMService{}
+	// Every response is 14 bytes, so two of them fit.
+	service, err := NewCachedService(mock, dir, WithCacheMaxSize(30))
+	if err != nil {
+		t.Fatalf("NewCachedService() error = %v", err)
+	}
+
+	for i, prompt := range []string{"first", "second", "third"} {
+		if _, err := service.GenerateContent(context.Background(), prompt, nil); err != nil {
+			t.Fatalf("GenerateContent() error = %v", err)
+		}
+		// Keep modification times apart on coarse grained file systems.
+		modTime := time.Now().Add(time.Duration(i-3) * time.Minute)
+		if err := os.Chtimes(service.path(service.key(prompt, nil)), modTime, modTime); err != nil {
+			t.Fatalf("Failed to set cache file time: %v", err)
+		}
+	}
+
+	if files := cacheFiles(t, dir); len(files) != 2 {
+		t.Errorf("Expected the cache to be limited to 2 responses, got %d", len(files))
+	}
+	if _, err := os.Stat(service.path(service.key("first", nil))); err == nil {
+		t.Error("Expected the oldest response to be dropped")
+	}
+}
+
+func TestCachedService_WithoutCache(t *testing.T) {
+	dir := t.TempDir()
+	mock := &countingLLMService{}
+	service, err := NewCachedService(mock, dir)
+	if err != nil {
+		t.Fatalf("NewCachedService() error = %v", err)
+	}
+
+	ctx := WithoutCache(context.Background())
+	for range 2 {
+		if _, err := service.GenerateContent(ctx, "prompt", nil); err != nil {
+			t.Fatalf("GenerateContent() error = %v", err)
+		}
+	}
+	if mock.calls != 2 {
+		t.Errorf("Expected every request to reach the wrapped service, got %d calls", mock.calls)
+	}
+	if files := cacheFiles(t, dir); len(files) != 0 {
+		t.Errorf("Expected nothing to be cached, got %d files", len(files))
+	}
+}
+
+func TestCachedService_Identity(t *testing.T) {
+	dir := t.TempDir()
+	first := &countingLLMService{}
+	service, err := NewCachedService(first, dir)
+	if err != nil {
+		t.Fatalf("NewCachedService() error = %v", err)
+	}
+	if _, err := service.GenerateContent(context.Background(), "prompt", nil); err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+
+	second := &countingLLMService{cacheKey: "other-model"}
+	service, err = NewCachedService(second, dir)
+	if err != nil {
+		t.Fatalf("NewCachedService() error = %v", err)
+	}
+	if _, err := service.GenerateContent(context.Background(), "prompt", nil); err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+	if second.calls != 1 {
+		t.Errorf("Expected a service with another cache key to miss the cache, got %d calls", second.calls)
+	}
+
+	keys := map[string]bool{}
+	for _, service := range []interface{ CacheKey() string }{
+		NewOpenAIService("http://localhost:11434/v1", ""),
+		NewOpenAIService("http://localhost:8080/v1", ""),
+		NewOpenAIService("http://localhost:8080/v1", "", WithModel("llama3")),
+		NewOpenAIService("http://localhost:8080/v1", "", WithModel("llama3"), WithTemperature(0.2)),
+		NewOpenAIService("http://localhost:8080/v1", "", WithModel("llama3"), WithTemperature(0.2), WithMaxOutputTokens(512)),
+	} {
+		keys[service.CacheKey()] = true
+	}
+	gemini, err := NewGeminiService("")
+	if err != nil {
+		t.Fatalf("NewGeminiService() error = %v", err)
+	}
+	keys[gemini.CacheKey()] = true
+	if len(keys) != 6 {
+		t.Errorf("Expected the provider, base URL, model, temperature and max output tokens to change the cache key, got %v", keys)
+	}
+}
+
+func TestCachedService_PrunesExpiredOnOpen(t *testing.T) {
+	dir := t.TempDir()
+	mock := &countingLLMService{}
+	service, err := NewCachedService(mock, dir, WithCacheTTL(time.Hour))
+	if err != nil {
+		t.Fatalf("NewCachedService() error = %v", err)
+	}
+	if _, err := service.GenerateContent(context.Background(), "prompt", nil); err != nil {
+		t.Fatalf("GenerateContent() error = %v", err)
+	}
+	expired := time.Now().Add(-2 * time.Hour)
+	for _, file := range cacheFiles(t, dir) {
+		if err := os.Chtimes(file, expired, expired); err != nil {
+			t.Fatalf("Failed to age cache file: %v", err)
+		}
+	}
+
+	if _, err := NewCachedService(mock, dir, WithCacheTTL(time.Hour)); err != nil {
+		t.Fatalf("NewCachedService() error = %v", err)
+	}
+	if files := cacheFiles(t, dir); len(files) != 0 {
+		t.Errorf("Expected expired responses to be removed when the cache is opened, got %d", len(files))
+	}
+}
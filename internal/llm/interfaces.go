@@ -2,10 +2,8 @@ package llm
 
 import (
 	"context"
-
-	"google.golang.org/genai"
 )
 
 type LLMService interface {
-	GenerateContent(ctx context.Context, prompt string, schema *genai.Schema) (string, error)
-}
\ No newline at end of file
+	GenerateContent(ctx context.Context, prompt string, schema *Schema) (string, error)
+}
@@ -3,11 +3,82 @@ package llm
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"google.golang.org/genai"
 )
 
-const GeminiModel = "gemini-1.5-flash"
+const (
+	GeminiModel    = "gemini-2.5-flash"
+	DefaultTimeout = 2 * time.Minute
+)
+
+type options struct {
+	model           string
+	temperature     *float64
+	maxOutputTokens int
+	timeout         time.Duration
+}
+
+type Option func(*options)
+
+// WithModel overrides the default model of a provider.
+func WithModel(model string) Option {
+	return func(o *options) {
+		if model != "" {
+			o.model = model
+		}
+	}
+}
+
+// WithTemperature sets the sampling temperature instead of the provider
+// default.
+func WithTemperature(temperature float64) Option {
+	return func(o *options) {
+		o.temperature = &temperature
+	}
+}
+
+// WithMaxOutputTokens limits the length of responses, 0 keeps the provider
+// default.
+func WithMaxOutputTokens(tokens int) Option {
+	return func(o *options) {
+		o.maxOutputTokens = tokens
+	}
+}
+
+// WithTimeout overrides DefaultTimeout, 0 disables it.
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// cacheKey describes the options that change a response.
+func (o options) cacheKey() string {
+	temperature := "default"
+	if o.temperature != nil {
+		temperature = fmt.Sprint(*o.temperature)
+	}
+	return fmt.Sprintf("model=%s temperature=%s max_output_tokens=%d", o.model, temperature, o.maxOutputTokens)
+}
+
+func newOptions(model string, opts []Option) options {
+	o := options{model: model, timeout: DefaultTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// withTimeout bounds a request by the configured timeout.
+func (o *options) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, o.timeout)
+}
 
 type modelClient interface {
 	GenerateContent(ctx context.Context, model string, parts []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
@@ -16,11 +87,16 @@ type modelClient interface {
 type GeminiService struct {
 	client    modelClient
 	modelName string
+	options   options
 }
 
-func NewGeminiService(apiKey string) (*GeminiService, error) {
+var _ LLMService = (*GeminiService)(nil)
+
+func NewGeminiService(apiKey string, opts ...Option) (*GeminiService, error) {
+	o := newOptions(GeminiModel, opts)
+
 	if apiKey == "" {
-		return &GeminiService{modelName: GeminiModel}, nil
+		return &GeminiService{modelName: o.model, options: o}, nil
 	}
 
 	ctx := context.Background()
@@ -30,18 +106,33 @@ func NewGeminiService(apiKey string) (*GeminiService, error) {
 		return nil, err
 	}
 
-	return &GeminiService{client: client.Models, modelName: GeminiModel}, nil
+	return &GeminiService{client: client.Models, modelName: o.model, options: o}, nil
+}
+
+// CacheKey identifies the provider, model and options responses are
+// generated with, for the CachedService.
+func (s *GeminiService) CacheKey() string {
+	return "provider=gemini " + s.options.cacheKey()
 }
 
-func (s *GeminiService) GenerateContent(ctx context.Context, prompt string, schema *genai.Schema) (string, error) {
+func (s *GeminiService) GenerateContent(ctx context.Context, prompt string, schema *Schema) (string, error) {
 	if s.client == nil {
 		return "", errors.New("LLM service is disabled: no API key provided")
 	}
 
-	resp, err := s.client.GenerateContent(ctx, s.modelName, genai.Text(prompt), &genai.GenerateContentConfig{
+	ctx, cancel := s.options.withTimeout(ctx)
+	defer cancel()
+
+	config := &genai.GenerateContentConfig{
 		ResponseMIMEType: "application/json",
-		ResponseSchema:   schema,
-	})
+		ResponseSchema:   schema.genai(),
+		MaxOutputTokens:  int32(s.options.maxOutputTokens),
+	}
+	if s.options.temperature != nil {
+		config.Temperature = genai.Ptr(float32(*s.options.temperature))
+	}
+
+	resp, err := s.client.GenerateContent(ctx, s.modelName, genai.Text(prompt), config)
 	if err != nil {
 		return "", err
 	}
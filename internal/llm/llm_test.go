@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"google.golang.org/genai"
 )
@@ -11,7 +12,7 @@ import (
 // MockLLMService is a mock implementation of the llm.LLMService interface.
 type MockLLMService struct{}
 
-func (m *MockLLMService) GenerateContent(ctx context.Context, prompt string, schema *genai.Schema) (string, error) {
+func (m *MockLLMService) GenerateContent(ctx context.Context, prompt string, schema *Schema) (string, error) {
 	return "", nil
 }
 
@@ -39,6 +40,17 @@ func TestNewGeminiService(t *testing.T) {
 	if service.client != nil {
 		t.Error("Service client should be nil for empty API key")
 	}
+	if service.modelName != GeminiModel {
+		t.Errorf("Expected the default model %s, got %s", GeminiModel, service.modelName)
+	}
+
+	service, err = NewGeminiService("", WithModel("gemini-2.0-flash"))
+	if err != nil {
+		t.Fatalf("NewGeminiService should not return an error, but got: %v", err)
+	}
+	if service.modelName != "gemini-2.0-flash" {
+		t.Errorf("Expected ai.model to override the default model, got %s", service.modelName)
+	}
 }
 
 func TestGeminiService_GenerateContent_Success(t *testing.T) {
@@ -125,3 +137,59 @@ func TestGeminiService_GenerateContent_EmptyResponse(t *testing.T) {
 		t.Errorf("Expected error message to be 'no content returned from LLM', but got: %s", err.Error())
 	}
 }
+
+func TestGeminiService_GenerateContent_Schema(t *testing.T) {
+	var got *genai.Schema
+	mockClient := &mockModelClient{
+		GenerateContentFunc: func(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+			got = config.ResponseSchema
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: []*genai.Part{{Text: "{}"}}}}},
+			}, nil
+		},
+	}
+
+	service := &GeminiService{client: mockClient, modelName: "test-model"}
+	if _, err := service.GenerateContent(context.Background(), "test prompt", &Schema{
+		Type: TypeObject,
+		Properties: map[string]*Schema{
+			"ids": {Type: TypeArray, Items: &Schema{Type: TypeInteger}},
+		},
+	}); err != nil {
+		t.Fatalf("GenerateContent should not return an error, but got: %v", err)
+	}
+
+	if got == nil || got.Type != genai.TypeObject || got.Properties["ids"].Type != genai.TypeArray || got.Properties["ids"].Items.Type != genai.TypeInteger {
+		t.Errorf("Expected the schema to be converted to a Gemini schema, got %+v", got)
+	}
+}
+
+func TestGeminiService_GenerationOptions(t *testing.T) {
+	var got *genai.GenerateContentConfig
+	var deadline time.Time
+	mockClient := &mockModelClient{
+		GenerateContentFunc: func(ctx context.Context, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+			got = config
+			deadline, _ = ctx.Deadline()
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{{Content: &genai.Content{Parts: []*genai.Part{{Text: "{}"}}}}},
+			}, nil
+		},
+	}
+
+	o := newOptions(GeminiModel, []Option{WithTemperature(0.2), WithMaxOutputTokens(1024), WithTimeout(time.Minute)})
+	service := &GeminiService{client: mockClient, modelName: o.model, options: o}
+	if _, err := service.GenerateContent(context.Background(), "test prompt", nil); err != nil {
+		t.Fatalf("GenerateContent should not return an error, but got: %v", err)
+	}
+
+	if got.Temperature == nil || *got.Temperature != 0.2 {
+		t.Errorf("Expected temperature 0.2, got %v", got.Temperature)
+	}
+	if got.MaxOutputTokens != 1024 {
+		t.Errorf("Expected 1024 max output tokens, got %d", got.MaxOutputTokens)
+	}
+	if remaining := time.Until(deadline); remaining <= 0 || remaining > time.Minute {
+		t.Errorf("Expected the request to be bound by the one minute timeout, got %v", remaining)
+	}
+}
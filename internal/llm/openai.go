@@ -0,0 +1,133 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	OpenAIBaseURL = "https://api.openai.com/v1"
+	OpenAIModel   = "gpt-4o-mini"
+)
+
+// OpenAIService talks to any OpenAI compatible chat completions API, such as
+// OpenAI itself, Ollama, the llama.cpp server or vLLM.
+type OpenAIService struct {
+	client    *http.Client
+	baseURL   string
+	apiKey    string
+	modelName string
+	options   options
+}
+
+var _ LLMService = (*OpenAIService)(nil)
+
+func NewOpenAIService(baseURL, apiKey string, opts ...Option) *OpenAIService {
+	o := newOptions(OpenAIModel, opts)
+
+	if baseURL == "" {
+		baseURL = OpenAIBaseURL
+	}
+
+	return &OpenAIService{
+		client:    http.DefaultClient,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		apiKey:    apiKey,
+		modelName: o.model,
+		options:   o,
+	}
+}
+
+// CacheKey identifies the provider, server, model and options responses are
+// generated with, for the CachedService.
+func (s *OpenAIService) CacheKey() string {
+	return "provider=openai base_url=" + s.baseURL + " " + s.options.cacheKey()
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type responseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *jsonSchema `json:"json_schema,omitempty"`
+}
+
+type jsonSchema struct {
+	Name   string  `json:"name"`
+	Schema *Schema `json:"schema"`
+}
+
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+	Temperature    *float64        `json:"temperature,omitempty"`
+	MaxTokens      int             `json:"max_tokens,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (s *OpenAIService) GenerateContent(ctx context.Context, prompt string, schema *Schema) (string, error) {
+	request := chatRequest{
+		Model:       s.modelName,
+		Messages:    []chatMessage{{Role: "user", Content: prompt}},
+		Temperature: s.options.temperature,
+		MaxTokens:   s.options.maxOutputTokens,
+	}
+	if schema != nil {
+		request.ResponseFormat = &responseFormat{
+			Type:       "json_schema",
+			JSONSchema: &jsonSchema{Name: "response", Schema: schema},
+		}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := s.options.withTimeout(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("LLM request failed with status %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	var response chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode LLM response: %w", err)
+	}
+
+	if len(response.Choices) == 0 || response.Choices[0].Message.Content == "" {
+		return "", errors.New("no content returned from LLM")
+	}
+	return response.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,147 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSchema = &Schema{
+	Type: TypeObject,
+	Properties: map[string]*Schema{
+		"summary": {Type: TypeString},
+		"ids":     {Type: TypeArray, Items: &Schema{Type: TypeInteger}},
+	},
+}
+
+func TestOpenAIService_GenerateContent_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer test-key" {
+			t.Errorf("Expected bearer authorization, got %q", got)
+		}
+
+		var request map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if request["model"] != "llama3.1" {
+			t.Errorf("Expected model llama3.1, got %v", request["model"])
+		}
+		messages := request["messages"].([]any)
+		if len(messages) != 1 || messages[0].(map[string]any)["content"] != "test prompt" {
+			t.Errorf("Expected the prompt as the only message, got %v", messages)
+		}
+		format := request["response_format"].(map[string]any)
+		schema := format["json_schema"].(map[string]any)["schema"].(map[string]any)
+		if format["type"] != "json_schema" || schema["type"] != "object" {
+			t.Errorf("Expected a JSON schema response format, got %v", format)
+		}
+		items := schema["properties"].(map[string]any)["ids"].(map[string]any)["items"].(map[string]any)
+		if items["type"] != "integer" {
+			t.Errorf("Expected nested schemas to be sent, got %v", schema)
+		}
+
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"summary\":\"ok\"}"}}]}`))
+	}))
+	defer server.Close()
+
+	service := NewOpenAIService(server.URL+"/v1/", "test-key", WithModel("llama3.1"))
+	resp, err := service.GenerateContent(context.Background(), "test prompt", testSchema)
+	if err != nil {
+		t.Fatalf("GenerateContent should not return an error, but got: %v", err)
+	}
+	if resp != `{"summary":"ok"}` {
+		t.Errorf("Expected the message content, got %s", resp)
+	}
+}
+
+func TestOpenAIService_GenerateContent_NoApiKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "" {
+			t.Errorf("Expected no authorization header without an API key, got %q", got)
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"local"}}]}`))
+	}))
+	defer server.Close()
+
+	service := NewOpenAIService(server.URL, "")
+	if service.modelName != OpenAIModel {
+		t.Errorf("Expected the default model %s, got %s", OpenAIModel, service.modelName)
+	}
+	if resp, err := service.GenerateContent(context.Background(), "test prompt", nil); err != nil || resp != "local" {
+		t.Errorf("Expected the local response, got %q, %v", resp, err)
+	}
+}
+
+func TestOpenAIService_GenerateContent_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{"error status", http.StatusUnauthorized, `{"error":{"message":"invalid api key"}}`, "invalid api key"},
+		{"no choices", http.StatusOK, `{"choices":[]}`, "no content returned from LLM"},
+		{"invalid json", http.StatusOK, `not json`, "failed to decode LLM response"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewOpenAIService(server.URL, "test-key").GenerateContent(context.Background(), "test prompt", testSchema)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestOpenAIService_GenerationOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if request["temperature"] != 0.0 || request["max_tokens"] != 512.0 {
+			t.Errorf("Expected temperature 0 and max_tokens 512, got %v and %v", request["temperature"], request["max_tokens"])
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	service := NewOpenAIService(server.URL, "", WithTemperature(0), WithMaxOutputTokens(512))
+	if _, err := service.GenerateContent(context.Background(), "test prompt", nil); err != nil {
+		t.Fatalf("GenerateContent should not return an error, but got: %v", err)
+	}
+}
+
+func TestOpenAIService_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	service := NewOpenAIService(server.URL, "", WithTimeout(50*time.Millisecond))
+	_, err := service.GenerateContent(context.Background(), "test prompt", nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the request to time out, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+type SchemaType string
+
+const (
+	TypeObject  SchemaType = "object"
+	TypeArray   SchemaType = "array"
+	TypeString  SchemaType = "string"
+	TypeInteger SchemaType = "integer"
+	TypeNumber  SchemaType = "number"
+	TypeBoolean SchemaType = "boolean"
+)
+
+// Schema describes the JSON response expected from the model. It marshals to
+// JSON Schema and only uses the subset every provider supports.
+type Schema struct {
+	Type        SchemaType         `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+}
+
+func (s *Schema) genai() *genai.Schema {
+	if s == nil {
+		return nil
+	}
+
+	schema := &genai.Schema{
+		Type:        genai.Type(strings.ToUpper(string(s.Type))),
+		Description: s.Description,
+		Required:    s.Required,
+		Items:       s.Items.genai(),
+	}
+	if len(s.Properties) > 0 {
+		schema.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, property := range s.Properties {
+			schema.Properties[name] = property.genai()
+		}
+	}
+	return schema
+}
@@ -11,16 +11,54 @@ type FeedIcon struct {
 }
 
 type HTMLTemplateData struct {
-	Category      *miniflux.Category
-	Entries       *miniflux.Entries
-	GeneratedDate time.Time
-	FeedIcons     []*FeedIcon
-	EntryGroups   []*EntryGroup
-	Summary       string
-	MinifluxHost  string
+	Category         *miniflux.Category
+	Entries          *miniflux.Entries
+	GeneratedDate    time.Time
+	FeedIcons        []*FeedIcon
+	EntryGroups      []*EntryGroup
+	Summary          string
+	MinifluxHost     string
+	RemainingEntries int
 }
 
 type EntryGroup struct {
 	Title   string
-	Entries []*miniflux.Entry
+	Entries []*Entry
+}
+
+// Entry is an entry as shown in a digest, with its TL;DR when
+// ai.entry_summaries is enabled.
+type Entry struct {
+	*miniflux.Entry
+	TLDR string
+}
+
+// FeedIcon returns the icon of a feed, or nil when the feed has none.
+func (d HTMLTemplateData) FeedIcon(feedID int64) *FeedIcon {
+	for _, icon := range d.FeedIcons {
+		if icon.FeedID == feedID {
+			return icon
+		}
+	}
+	return nil
+}
+
+// HasTLDRs reports whether any entry of the digest has a TL;DR.
+func (d HTMLTemplateData) HasTLDRs() bool {
+	for _, group := range d.EntryGroups {
+		for _, entry := range group.Entries {
+			if entry.TLDR != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EntryCount returns the number of entries in the digest.
+func (d HTMLTemplateData) EntryCount() int {
+	if d.Entries == nil {
+		return 0
+	}
+	return len(*d.Entries)
 }
@@ -0,0 +1,97 @@
+package outbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"miniflux-digest/internal/app"
+	"miniflux-digest/internal/utils"
+)
+
+// FileOutbox stores every pending delivery as a JSON file in a directory, so
+// failed emails survive restarts.
+type FileOutbox struct {
+	dir string
+	mu  sync.Mutex
+}
+
+var _ app.Outbox = (*FileOutbox)(nil)
+
+func NewFileOutbox(dir string) (*FileOutbox, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+	return &FileOutbox{dir: dir}, nil
+}
+
+func (o *FileOutbox) path(id string) string {
+	return filepath.Join(o.dir, id+".json")
+}
+
+// Save writes the delivery atomically, so a crash never leaves a partially
+// written delivery behind.
+func (o *FileOutbox) Save(delivery *app.PendingDelivery) error {
+	if delivery.ID == "" || strings.ContainsAny(delivery.ID, `/\`) {
+		return fmt.Errorf("invalid delivery id %q", delivery.ID)
+	}
+
+	data, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return utils.WriteFileAtomic(o.path(delivery.ID), data)
+}
+
+func (o *FileOutbox) Remove(id string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if err := os.Remove(o.path(id)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// List returns the pending deliveries, oldest first. Files that cannot be
+// read are logged and skipped.
+func (o *FileOutbox) List() ([]*app.PendingDelivery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(o.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*app.PendingDelivery, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("Error reading outbox file %s: %v", path, err)
+			continue
+		}
+
+		var delivery app.PendingDelivery
+		if err := json.Unmarshal(data, &delivery); err != nil {
+			log.Printf("Error decoding outbox file %s: %v", path, err)
+			continue
+		}
+		deliveries = append(deliveries, &delivery)
+	}
+
+	slices.SortFunc(deliveries, func(a, b *app.PendingDelivery) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return deliveries, nil
+}
@@ -0,0 +1,90 @@
+package outbox
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/app"
+	"miniflux-digest/internal/models"
+)
+
+func newDelivery(id string, created time.Time) *app.PendingDelivery {
+	return &app.PendingDelivery{
+		ID: id,
+		Data: &models.HTMLTemplateData{
+			Category: &miniflux.Category{ID: 7, Title: "Security"},
+			Entries:  &miniflux.Entries{{ID: 1, Title: "Entry"}},
+		},
+		FilePath:  "/archive/security/2025-01-01.html",
+		Attempts:  1,
+		CreatedAt: created,
+	}
+}
+
+func TestFileOutbox(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	dir := filepath.Join(t.TempDir(), "outbox")
+	outbox, err := NewFileOutbox(dir)
+	if err != nil {
+		t.Fatalf("NewFileOutbox failed: %v", err)
+	}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := outbox.Save(newDelivery("newer", now)); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := outbox.Save(newDelivery("older", now.Add(-time.Hour))); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0600); err != nil {
+		t.Fatalf("Failed to write broken file: %v", err)
+	}
+
+	reopened, err := NewFileOutbox(dir)
+	if err != nil {
+		t.Fatalf("NewFileOutbox failed: %v", err)
+	}
+	deliveries, err := reopened.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(deliveries) != 2 || deliveries[0].ID != "older" || deliveries[1].ID != "newer" {
+		t.Fatalf("Expected deliveries to survive a restart oldest first, got %+v", deliveries)
+	}
+	if deliveries[1].Data.Category.Title != "Security" || len(*deliveries[1].Data.Entries) != 1 || !deliveries[1].CreatedAt.Equal(now) {
+		t.Errorf("Expected delivery data to round trip, got %+v", deliveries[1])
+	}
+
+	updated := deliveries[0]
+	updated.Attempts = 3
+	updated.LastError = "smtp down"
+	if err := reopened.Save(updated); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if err := reopened.Remove("newer"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := reopened.Remove("missing"); err != nil {
+		t.Errorf("Expected removing a missing delivery to succeed, got %v", err)
+	}
+
+	deliveries, err = reopened.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Attempts != 3 || deliveries[0].LastError != "smtp down" {
+		t.Errorf("Expected only the updated delivery to remain, got %+v", deliveries)
+	}
+
+	if err := reopened.Save(newDelivery("../escape", now)); err == nil {
+		t.Error("Expected error for a delivery id containing a path separator")
+	}
+}
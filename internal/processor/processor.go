@@ -1,16 +1,108 @@
 package processor
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"strings"
+	"time"
 
 	"miniflux-digest/internal/app"
+	"miniflux-digest/internal/config"
+	"miniflux-digest/internal/models"
+
+	miniflux "miniflux.app/v2/client"
+)
+
+const (
+	RetryBaseDelay = time.Minute
+	RetryMaxDelay  = 6 * time.Hour
 )
 
-func CategoryDigestJob(application *app.App, rawData *app.RawCategoryData, markAsRead bool) {
-	data := application.DigestService.BuildDigestData(rawData.Category, rawData.Entries, rawData.Icons, application.Config.Digest.GroupBy, application.Config.Miniflux.Host)
+// retryDelay doubles the wait after every failed attempt, up to RetryMaxDelay.
+func retryDelay(attempts int) time.Duration {
+	delay := RetryBaseDelay
+	for i := 1; i < attempts && delay < RetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, RetryMaxDelay)
+}
+
+func entryIDs(data *models.HTMLTemplateData) []int64 {
+	if data.Entries == nil {
+		return nil
+	}
+	ids := make([]int64, 0, len(*data.Entries))
+	for _, entry := range *data.Entries {
+		ids = append(ids, entry.ID)
+	}
+	return ids
+}
+
+// shouldMarkAsRead reports whether the entries of a digest are marked as
+// read. For on_success a digest waiting in the outbox is not sent yet, its
+// entries are marked as read when a retry succeeds.
+func shouldMarkAsRead(policy config.MarkAsReadPolicy, sent bool) bool {
+	switch policy {
+	case config.MarkAsReadAlways:
+		return true
+	case config.MarkAsReadNever:
+		return false
+	default:
+		return sent
+	}
+}
+
+// withoutQueuedEntries leaves out the entries of digests waiting in the
+// outbox, so an entry left unread while its digest is retried is not sent
+// twice. Once outbox.max_age drops a delivery, its entries are digested
+// again.
+func withoutQueuedEntries(application *app.App, entries *miniflux.Entries) *miniflux.Entries {
+	deliveries, err := application.Outbox.List()
+	if err != nil {
+		log.Printf("Error reading outbox: %v", err)
+		return entries
+	}
+
+	queued := make(map[int64]bool)
+	for _, delivery := range deliveries {
+		for _, id := range entryIDs(delivery.Data) {
+			queued[id] = true
+		}
+	}
+	if len(queued) == 0 {
+		return entries
+	}
+
+	var kept miniflux.Entries
+	for _, entry := range *entries {
+		if !queued[entry.ID] {
+			kept = append(kept, entry)
+		}
+	}
+	return &kept
+}
+
+func buildDigest(application *app.App, rawData *app.RawCategoryData) (*config.Config, *models.HTMLTemplateData) {
+	cfg := application.Config
+	if rawData.Category != nil {
+		cfg = cfg.ForCategory(rawData.Category.ID, rawData.Category.Title)
+	}
+
+	entries := withoutQueuedEntries(application, rawData.Entries)
+	data := application.DigestService.BuildDigestData(rawData.Category, entries, rawData.Icons, cfg.Digest.GroupBy, cfg.Miniflux.Host)
+	data.RemainingEntries = max(rawData.Total-len(*rawData.Entries), 0)
+
+	return cfg, data
+}
+
+func CategoryDigestJob(application *app.App, rawData *app.RawCategoryData) {
+	cfg, data := buildDigest(application, rawData)
 
 	if len(*data.Entries) > 0 {
-		file, err := application.ArchiveService.MakeArchiveHTML(data, application.Config.Digest.Compress)
+		file, err := application.ArchiveService.MakeArchiveHTML(data, cfg.Digest.Compress)
 		if err != nil {
 			log.Printf("Error generating File for category %s: %v", data.Category.Title, err)
 			return
@@ -21,16 +113,204 @@ func CategoryDigestJob(application *app.App, rawData *app.RawCategoryData, markA
 			}
 		}()
 
-		err = application.EmailService.Send(application.Config, file, data)
+		sendErr := application.EmailService.Send(cfg, file, data)
+
+		queued := false
+		if sendErr != nil {
+			log.Printf("Error sending email for category '%s': %v", data.Category.Title, sendErr)
+			queued = queueDelivery(application, data, file.Name(), sendErr)
+		}
+
+		switch {
+		case shouldMarkAsRead(cfg.Digest.MarkAsReadPolicy, sendErr == nil):
+			markAsRead(application, data)
+		case queued:
+			log.Printf("Entries for category '%s' were left unread until the queued delivery is sent", data.Category.Title)
+		case sendErr != nil:
+			log.Printf("Entries for category '%s' were left unread and will be included in the next digest", data.Category.Title)
+		}
+	}
+}
+
+func markAsRead(application *app.App, data *models.HTMLTemplateData) {
+	if err := application.MinifluxClientService.MarkEntriesAsRead(entryIDs(data)); err != nil {
+		log.Printf("Error marking entries as read for category '%s': %v", data.Category.Title, err)
+	}
+}
+
+// DryRunCategoryDigestJob builds and archives a digest like CategoryDigestJob
+// but only reports what would be sent and marked as read, without sending
+// email or updating Miniflux.
+func DryRunCategoryDigestJob(application *app.App, rawData *app.RawCategoryData, w io.Writer) error {
+	cfg, data := buildDigest(application, rawData)
+
+	if _, err := fmt.Fprintf(w, "Category: %s (%d)\n", data.Category.Title, data.Category.ID); err != nil {
+		return err
+	}
+
+	if len(*data.Entries) == 0 {
+		_, err := fmt.Fprintln(w, "  No unread entries, nothing would be sent")
+		return err
+	}
 
+	file, err := application.ArchiveService.MakeArchiveHTML(data, cfg.Digest.Compress)
+	if err != nil {
+		return fmt.Errorf("error generating file for category '%s': %w", data.Category.Title, err)
+	}
+	if err := file.Close(); err != nil {
+		log.Printf("Error closing file for category '%s': %v", data.Category.Title, err)
+	}
+
+	recipients, err := subscribedRecipients(application, cfg, data.Category.ID)
+	if err != nil {
+		return fmt.Errorf("error reading suppression list for category '%s': %w", data.Category.Title, err)
+	}
+
+	var wouldMark []int64
+	if shouldMarkAsRead(cfg.Digest.MarkAsReadPolicy, true) {
+		wouldMark = entryIDs(data)
+	}
+
+	_, err = fmt.Fprintf(w, "  Archive: %s\n  From: %s\n  To: %s\n  Entries: %d (%d more not shown)\n  Groups: %d\n  Would mark as read: %v\n",
+		file.Name(),
+		cfg.Digest.Email.From,
+		strings.Join(recipients, ", "),
+		len(*data.Entries),
+		data.RemainingEntries,
+		len(data.EntryGroups),
+		wouldMark,
+	)
+	return err
+}
+
+// subscribedRecipients returns the recipients of a category that did not
+// unsubscribe from it.
+func subscribedRecipients(application *app.App, cfg *config.Config, categoryID int64) ([]string, error) {
+	recipients := cfg.Digest.Email.Recipients()
+	if application.Suppressions == nil {
+		return recipients, nil
+	}
+
+	var subscribed []string
+	for _, recipient := range recipients {
+		suppressed, err := application.Suppressions.IsSuppressed(categoryID, recipient)
 		if err != nil {
-			log.Printf("Error sending email for category '%s': %v", data.Category.Title, err)
+			return nil, err
 		}
+		if !suppressed {
+			subscribed = append(subscribed, recipient)
+		}
+	}
+	return subscribed, nil
+}
+
+// queueDelivery saves a failed digest to the outbox and reports whether it
+// will be retried. Only the recipients that did not get an individually sent
+// copy are retried.
+func queueDelivery(application *app.App, data *models.HTMLTemplateData, filePath string, sendErr error) bool {
+	now := time.Now()
+	delivery := &app.PendingDelivery{
+		ID:          fmt.Sprintf("%d-%d", data.Category.ID, now.UnixNano()),
+		Data:        data,
+		FilePath:    filePath,
+		Recipients:  undeliveredRecipients(sendErr),
+		Attempts:    1,
+		CreatedAt:   now,
+		NextAttempt: now.Add(retryDelay(1)),
+		LastError:   sendErr.Error(),
+	}
 
-		if markAsRead {
-			if err := application.MinifluxClientService.MarkCategoryAsRead(data.Category.ID); err != nil {
-				log.Printf("Error marking category as read for category '%s': %v", data.Category.Title, err)
+	if err := application.Outbox.Save(delivery); err != nil {
+		log.Printf("Error queueing delivery for category '%s': %v", data.Category.Title, err)
+		return false
+	}
+	log.Printf("Delivery for category '%s' queued for retry at %s", data.Category.Title, delivery.NextAttempt.Format(time.RFC3339))
+	return true
+}
+
+func undeliveredRecipients(err error) []string {
+	var undelivered *app.UndeliveredError
+	if errors.As(err, &undelivered) {
+		return undelivered.Recipients
+	}
+	return nil
+}
+
+func retryDelivery(application *app.App, cfg *config.Config, delivery *app.PendingDelivery) error {
+	file, err := os.Open(delivery.FilePath)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Printf("Error closing file for category '%s': %v", delivery.Data.Category.Title, err)
+		}
+	}()
+
+	if len(delivery.Recipients) > 0 {
+		cfg.Digest.Email.To = delivery.Recipients
+		cfg.Digest.Email.Cc = nil
+		cfg.Digest.Email.Bcc = nil
+	}
+	return application.EmailService.Send(cfg, file, delivery.Data)
+}
+
+// RetryFailedDeliveries sends every outbox delivery that is due, backing off
+// exponentially after each failure, and drops deliveries older than
+// outbox.max_age. Under on_success, entries are marked as read once their
+// retry succeeds and left unread when their delivery is dropped, so the next
+// digest includes them.
+func RetryFailedDeliveries(application *app.App) {
+	deliveries, err := application.Outbox.List()
+	if err != nil {
+		log.Printf("Error reading outbox: %v", err)
+		return
+	}
+
+	now := time.Now()
+	maxAge := application.Config.Outbox.MaxAge
+	for _, delivery := range deliveries {
+		category := delivery.Data.Category
+		title := category.Title
+		cfg := application.Config.ForCategory(category.ID, title)
+
+		if maxAge > 0 && now.Sub(delivery.CreatedAt) > maxAge {
+			log.Printf("Dropping delivery for category '%s' queued at %s after %d attempts, last error: %s", title, delivery.CreatedAt.Format(time.RFC3339), delivery.Attempts, delivery.LastError)
+			if err := application.Outbox.Remove(delivery.ID); err != nil {
+				log.Printf("Error removing delivery %s from outbox: %v", delivery.ID, err)
 			}
+			if !shouldMarkAsRead(cfg.Digest.MarkAsReadPolicy, false) {
+				log.Printf("Entries for category '%s' were left unread and will be included in the next digest", title)
+			}
+			continue
+		}
+
+		if delivery.NextAttempt.After(now) {
+			continue
+		}
+
+		err := retryDelivery(application, cfg, delivery)
+		if err == nil {
+			log.Printf("Retried delivery for category '%s' succeeded", title)
+			if err := application.Outbox.Remove(delivery.ID); err != nil {
+				log.Printf("Error removing delivery %s from outbox: %v", delivery.ID, err)
+			}
+			// always already marked the entries when the first send failed.
+			if cfg.Digest.MarkAsReadPolicy != config.MarkAsReadAlways && shouldMarkAsRead(cfg.Digest.MarkAsReadPolicy, true) {
+				markAsRead(application, delivery.Data)
+			}
+			continue
+		}
+
+		if recipients := undeliveredRecipients(err); recipients != nil {
+			delivery.Recipients = recipients
+		}
+		delivery.Attempts++
+		delivery.LastError = err.Error()
+		delivery.NextAttempt = now.Add(retryDelay(delivery.Attempts))
+		log.Printf("Retried delivery for category '%s' failed, next attempt at %s: %v", title, delivery.NextAttempt.Format(time.RFC3339), err)
+		if err := application.Outbox.Save(delivery); err != nil {
+			log.Printf("Error updating delivery %s in outbox: %v", delivery.ID, err)
 		}
 	}
 }
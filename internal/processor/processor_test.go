@@ -10,8 +10,11 @@ import (
 	"miniflux-digest/internal/models"
 	"miniflux-digest/internal/testutil"
 	"miniflux-digest/internal/digest"
+	"miniflux-digest/internal/unsubscribe"
 	"os"
+	"slices"
 	"testing"
+	"time"
 
 	miniflux "miniflux.app/v2/client"
 )
@@ -33,7 +36,7 @@ func TestCategoryDigestJob(t *testing.T) {
 			app.WithEmailService(&testutil.MockEmailService{}),
 		)
 		data := &app.RawCategoryData{Entries: &miniflux.Entries{}}
-		CategoryDigestJob(mockApp, data, true)
+		CategoryDigestJob(mockApp, data)
 	})
 
 	t.Run("error making archive html", func(t *testing.T) {
@@ -57,7 +60,7 @@ func TestCategoryDigestJob(t *testing.T) {
 		var buf bytes.Buffer
 		log.SetOutput(&buf)
 
-		CategoryDigestJob(mockApp, data, true)
+		CategoryDigestJob(mockApp, data)
 
 		if !bytes.Contains(buf.Bytes(), []byte("archive generation failed")) {
 			t.Error("Expected error log for archive generation, but not found")
@@ -89,7 +92,7 @@ func TestCategoryDigestJob(t *testing.T) {
 		var buf bytes.Buffer
 		log.SetOutput(&buf)
 
-		CategoryDigestJob(mockApp, data, true)
+		CategoryDigestJob(mockApp, data)
 
 		if !bytes.Contains(buf.Bytes(), []byte("email sending failed")) {
 			t.Error("Expected error log for email sending, but not found")
@@ -97,10 +100,14 @@ func TestCategoryDigestJob(t *testing.T) {
 	})
 
 	t.Run("mark as read is called", func(t *testing.T) {
-		markAsReadCalled := false
+		var markedIDs []int64
 		mockMinifluxClient := &testutil.MockMinifluxClient{
 			MarkAsReadFunc: func(categoryID int64) error {
-				markAsReadCalled = true
+				t.Error("Expected MarkCategoryAsRead not to be called")
+				return nil
+			},
+			MarkEntriesAsReadFunc: func(entryIDs []int64) error {
+				markedIDs = entryIDs
 				return nil
 			},
 		}
@@ -109,7 +116,7 @@ func TestCategoryDigestJob(t *testing.T) {
 			app.WithMinifluxClientService(mockMinifluxClient),
 			app.WithDigestService(&testutil.MockDigestService{
 				BuildDigestDataFunc: func(category *miniflux.Category, entries *miniflux.Entries, icons map[int64]*models.FeedIcon, groupBy digest.GroupingType, minifluxHost string) *models.HTMLTemplateData {
-					return &models.HTMLTemplateData{Entries: &miniflux.Entries{{ID: 1}}, Category: &miniflux.Category{Title: "title"}, FeedIcons: []*models.FeedIcon{}}
+					return &models.HTMLTemplateData{Entries: &miniflux.Entries{{ID: 1}, {ID: 7}}, Category: &miniflux.Category{Title: "title"}, FeedIcons: []*models.FeedIcon{}}
 				},
 			}),
 			app.WithArchiveService(&testutil.MockArchiveService{
@@ -123,12 +130,12 @@ func TestCategoryDigestJob(t *testing.T) {
 				},
 			}),
 		)
-		data := &app.RawCategoryData{Entries: &miniflux.Entries{{ID: 1}}}
+		data := &app.RawCategoryData{Entries: &miniflux.Entries{{ID: 1}, {ID: 7}}}
 
-		CategoryDigestJob(mockApp, data, true)
+		CategoryDigestJob(mockApp, data)
 
-		if !markAsReadCalled {
-			t.Error("Expected MarkCategoryAsRead to be called, but it was not")
+		if len(markedIDs) != 2 || markedIDs[0] != 1 || markedIDs[1] != 7 {
+			t.Errorf("Expected entries [1 7] to be marked as read, got %v", markedIDs)
 		}
 	})
 
@@ -137,7 +144,7 @@ func TestCategoryDigestJob(t *testing.T) {
 		log.SetOutput(&buf)
 
 		mockMinifluxClient := &testutil.MockMinifluxClient{
-			MarkAsReadFunc: func(categoryID int64) error {
+			MarkEntriesAsReadFunc: func(entryIDs []int64) error {
 				return errors.New("mark as read failed")
 			},
 		}
@@ -162,10 +169,390 @@ func TestCategoryDigestJob(t *testing.T) {
 		)
 		data := &app.RawCategoryData{Entries: &miniflux.Entries{{ID: 1}}}
 
-		CategoryDigestJob(mockApp, data, true)
+		CategoryDigestJob(mockApp, data)
 
 		if !bytes.Contains(buf.Bytes(), []byte("mark as read failed")) {
 			t.Error("Expected error log for marking as read, but not found")
 		}
 	})
-}
\ No newline at end of file
+
+	t.Run("category overrides", func(t *testing.T) {
+		var sentTo []string
+		var groupedBy digest.GroupingType
+		mockApp := app.NewApp(
+			app.WithConfig(&config.Config{Digest: config.ConfigDigest{
+				GroupBy: digest.GroupingTypeDay,
+				Email:   config.ConfigDigestEmail{To: []string{"team@example.com"}},
+				Categories: map[string]config.ConfigCategory{
+					"Security": {Email: config.ConfigCategoryEmail{To: []string{"security@example.com", "lead@example.com"}}, GroupBy: digest.GroupingTypeFeed},
+				},
+			}}),
+			app.WithMinifluxClientService(&testutil.MockMinifluxClient{}),
+			app.WithDigestService(&testutil.MockDigestService{
+				BuildDigestDataFunc: func(category *miniflux.Category, entries *miniflux.Entries, icons map[int64]*models.FeedIcon, groupBy digest.GroupingType, minifluxHost string) *models.HTMLTemplateData {
+					groupedBy = groupBy
+					return &models.HTMLTemplateData{Entries: entries, Category: category}
+				},
+			}),
+			app.WithArchiveService(&testutil.MockArchiveService{
+				MakeArchiveHTMLFunc: func(data *models.HTMLTemplateData, compress bool) (*os.File, error) {
+					return os.CreateTemp(t.TempDir(), "test-archive-*.html")
+				},
+			}),
+			app.WithEmailService(&testutil.MockEmailService{
+				SendFunc: func(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error {
+					sentTo = cfg.Digest.Email.To
+					return nil
+				},
+			}),
+		)
+		data := &app.RawCategoryData{Category: &miniflux.Category{ID: 3, Title: "Security"}, Entries: &miniflux.Entries{{ID: 1}}}
+
+		CategoryDigestJob(mockApp, data)
+
+		if !slices.Equal(sentTo, []string{"security@example.com", "lead@example.com"}) {
+			t.Errorf("Expected digest to be sent to the category recipients, got %v", sentTo)
+		}
+		if groupedBy != digest.GroupingTypeFeed {
+			t.Errorf("Expected category group_by override to be used, got %q", groupedBy)
+		}
+	})
+
+	t.Run("mark as read policy", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			policy     config.MarkAsReadPolicy
+			sendErr    error
+			wantMarked bool
+			wantQueued int
+		}{
+			{name: "on_success with delivery", policy: config.MarkAsReadOnSuccess, wantMarked: true},
+			{name: "on_success with queued delivery", policy: config.MarkAsReadOnSuccess, sendErr: errors.New("smtp down"), wantQueued: 1},
+			{name: "always with queued delivery", policy: config.MarkAsReadAlways, sendErr: errors.New("smtp down"), wantMarked: true, wantQueued: 1},
+			{name: "never with delivery", policy: config.MarkAsReadNever},
+			{name: "never with queued delivery", policy: config.MarkAsReadNever, sendErr: errors.New("smtp down"), wantQueued: 1},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				marked := false
+				mockApp := app.NewApp(
+					app.WithConfig(&config.Config{Digest: config.ConfigDigest{MarkAsReadPolicy: tt.policy}}),
+					app.WithMinifluxClientService(&testutil.MockMinifluxClient{
+						MarkEntriesAsReadFunc: func(entryIDs []int64) error {
+							marked = true
+							return nil
+						},
+					}),
+					app.WithDigestService(&testutil.MockDigestService{
+						BuildDigestDataFunc: func(category *miniflux.Category, entries *miniflux.Entries, icons map[int64]*models.FeedIcon, groupBy digest.GroupingType, minifluxHost string) *models.HTMLTemplateData {
+							return &models.HTMLTemplateData{Entries: &miniflux.Entries{{ID: 1}}, Category: &miniflux.Category{Title: "title"}}
+						},
+					}),
+					app.WithArchiveService(&testutil.MockArchiveService{
+						MakeArchiveHTMLFunc: func(data *models.HTMLTemplateData, compress bool) (*os.File, error) {
+							return os.CreateTemp(t.TempDir(), "test-archive-*.html")
+						},
+					}),
+					app.WithEmailService(&testutil.MockEmailService{
+						SendFunc: func(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error {
+							return tt.sendErr
+						},
+					}),
+				)
+				data := &app.RawCategoryData{Entries: &miniflux.Entries{{ID: 1}}}
+
+				CategoryDigestJob(mockApp, data)
+
+				if marked != tt.wantMarked {
+					t.Errorf("Expected marked to be %v, got %v", tt.wantMarked, marked)
+				}
+				queued, _ := mockApp.Outbox.List()
+				if len(queued) != tt.wantQueued {
+					t.Errorf("Expected %d queued deliveries, got %d", tt.wantQueued, len(queued))
+				}
+			})
+		}
+	})
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Minute},
+		{attempts: 2, want: 2 * time.Minute},
+		{attempts: 4, want: 8 * time.Minute},
+		{attempts: 100, want: RetryMaxDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRetryFailedDeliveries(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	archive, err := os.CreateTemp(t.TempDir(), "test-archive-*.html")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatalf("Failed to close temp file: %v", err)
+	}
+
+	sendErr := errors.New("smtp down")
+	sends := 0
+	var markedIDs []int64
+	mockApp := app.NewApp(
+		app.WithConfig(&config.Config{Outbox: config.ConfigOutbox{MaxAge: 24 * time.Hour}}),
+		app.WithMinifluxClientService(&testutil.MockMinifluxClient{
+			MarkEntriesAsReadFunc: func(entryIDs []int64) error {
+				markedIDs = append(markedIDs, entryIDs...)
+				return nil
+			},
+		}),
+		app.WithEmailService(&testutil.MockEmailService{
+			SendFunc: func(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error {
+				sends++
+				return sendErr
+			},
+		}),
+	)
+	newDelivery := func(id string, created, next time.Time) *app.PendingDelivery {
+		return &app.PendingDelivery{
+			ID:          id,
+			Data:        &models.HTMLTemplateData{Category: &miniflux.Category{Title: "title"}, Entries: &miniflux.Entries{{ID: 1}}},
+			FilePath:    archive.Name(),
+			Attempts:    1,
+			CreatedAt:   created,
+			NextAttempt: next,
+		}
+	}
+	outboxLen := func() int {
+		deliveries, _ := mockApp.Outbox.List()
+		return len(deliveries)
+	}
+
+	now := time.Now()
+	_ = mockApp.Outbox.Save(newDelivery("due", now.Add(-time.Hour), now.Add(-time.Minute)))
+	_ = mockApp.Outbox.Save(newDelivery("later", now, now.Add(time.Hour)))
+	_ = mockApp.Outbox.Save(newDelivery("expired", now.Add(-48*time.Hour), now.Add(-time.Minute)))
+
+	RetryFailedDeliveries(mockApp)
+
+	if sends != 1 {
+		t.Errorf("Expected only the due delivery to be retried, got %d send attempts", sends)
+	}
+	if outboxLen() != 2 {
+		t.Fatalf("Expected the expired delivery to be dropped, got %d queued", outboxLen())
+	}
+	if len(markedIDs) != 0 {
+		t.Errorf("Expected the entries of failed and dropped deliveries to stay unread, got %v marked", markedIDs)
+	}
+
+	deliveries, _ := mockApp.Outbox.List()
+	due := deliveries[0]
+	if due.ID != "due" || due.Attempts != 2 || due.LastError != "smtp down" {
+		t.Errorf("Expected failed attempt to be recorded, got %+v", due)
+	}
+	if wait := time.Until(due.NextAttempt); wait < time.Minute || wait > 2*time.Minute {
+		t.Errorf("Expected next attempt to back off to 2 minutes, got %v", wait)
+	}
+
+	sendErr = nil
+	due.NextAttempt = now
+	_ = mockApp.Outbox.Save(due)
+
+	RetryFailedDeliveries(mockApp)
+
+	deliveries, _ = mockApp.Outbox.List()
+	if len(deliveries) != 1 || deliveries[0].ID != "later" {
+		t.Errorf("Expected successful delivery to be removed, got %d queued", len(deliveries))
+	}
+	if !slices.Equal(markedIDs, []int64{1}) {
+		t.Errorf("Expected the entries of the retried delivery to be marked as read, got %v", markedIDs)
+	}
+}
+
+func TestCategoryDigestJob_ExpiredDelivery(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var digested []int
+	marked := false
+	mockApp := app.NewApp(
+		app.WithConfig(&config.Config{
+			Digest: config.ConfigDigest{MarkAsReadPolicy: config.MarkAsReadOnSuccess},
+			Outbox: config.ConfigOutbox{MaxAge: time.Hour},
+		}),
+		app.WithMinifluxClientService(&testutil.MockMinifluxClient{
+			MarkEntriesAsReadFunc: func(entryIDs []int64) error {
+				marked = true
+				return nil
+			},
+		}),
+		app.WithDigestService(&testutil.MockDigestService{
+			BuildDigestDataFunc: func(category *miniflux.Category, entries *miniflux.Entries, icons map[int64]*models.FeedIcon, groupBy digest.GroupingType, minifluxHost string) *models.HTMLTemplateData {
+				digested = append(digested, len(*entries))
+				return &models.HTMLTemplateData{Entries: entries, Category: category}
+			},
+		}),
+		app.WithArchiveService(&testutil.MockArchiveService{
+			MakeArchiveHTMLFunc: func(data *models.HTMLTemplateData, compress bool) (*os.File, error) {
+				return os.CreateTemp(t.TempDir(), "test-archive-*.html")
+			},
+		}),
+		app.WithEmailService(&testutil.MockEmailService{
+			SendFunc: func(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error {
+				return errors.New("smtp down")
+			},
+		}),
+	)
+	rawData := func() *app.RawCategoryData {
+		return &app.RawCategoryData{Category: &miniflux.Category{ID: 1, Title: "title"}, Entries: &miniflux.Entries{{ID: 1}, {ID: 2}}}
+	}
+
+	CategoryDigestJob(mockApp, rawData())
+	deliveries, _ := mockApp.Outbox.List()
+	if len(deliveries) != 1 || marked {
+		t.Fatalf("Expected the failed digest to be queued with its entries unread, got %d queued and marked %v", len(deliveries), marked)
+	}
+
+	// The next digest leaves out the entries waiting in the outbox.
+	CategoryDigestJob(mockApp, rawData())
+
+	deliveries[0].CreatedAt = time.Now().Add(-2 * time.Hour)
+	_ = mockApp.Outbox.Save(deliveries[0])
+	RetryFailedDeliveries(mockApp)
+	if deliveries, _ := mockApp.Outbox.List(); len(deliveries) != 0 || marked {
+		t.Fatalf("Expected the expired delivery to be dropped with its entries unread, got %d queued and marked %v", len(deliveries), marked)
+	}
+
+	// Once dropped, the entries are digested again.
+	CategoryDigestJob(mockApp, rawData())
+	if !slices.Equal(digested, []int{2, 0, 2}) {
+		t.Errorf("Expected queued entries to be skipped until their delivery is dropped, got %v entries per digest", digested)
+	}
+}
+
+func TestRetryFailedDeliveries_Recipients(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var sentTo [][]string
+	sendErr := error(&app.UndeliveredError{Recipients: []string{"two@example.com", "three@example.com"}, Err: errors.New("smtp down")})
+	mockApp := app.NewApp(
+		app.WithConfig(&config.Config{Digest: config.ConfigDigest{Email: config.ConfigDigestEmail{
+			To:         []string{"one@example.com", "two@example.com"},
+			Bcc:        []string{"three@example.com"},
+			Individual: true,
+		}}}),
+		app.WithMinifluxClientService(&testutil.MockMinifluxClient{}),
+		app.WithDigestService(&testutil.MockDigestService{
+			BuildDigestDataFunc: func(category *miniflux.Category, entries *miniflux.Entries, icons map[int64]*models.FeedIcon, groupBy digest.GroupingType, minifluxHost string) *models.HTMLTemplateData {
+				return &models.HTMLTemplateData{Entries: entries, Category: category}
+			},
+		}),
+		app.WithArchiveService(&testutil.MockArchiveService{
+			MakeArchiveHTMLFunc: func(data *models.HTMLTemplateData, compress bool) (*os.File, error) {
+				return os.CreateTemp(t.TempDir(), "test-archive-*.html")
+			},
+		}),
+		app.WithEmailService(&testutil.MockEmailService{
+			SendFunc: func(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error {
+				sentTo = append(sentTo, cfg.Digest.Email.Recipients())
+				return sendErr
+			},
+		}),
+	)
+
+	CategoryDigestJob(mockApp, &app.RawCategoryData{Category: &miniflux.Category{ID: 1, Title: "title"}, Entries: &miniflux.Entries{{ID: 1}}})
+
+	deliveries, _ := mockApp.Outbox.List()
+	if len(deliveries) != 1 || !slices.Equal(deliveries[0].Recipients, []string{"two@example.com", "three@example.com"}) {
+		t.Fatalf("Expected the undelivered recipients to be queued, got %+v", deliveries)
+	}
+
+	sendErr = &app.UndeliveredError{Recipients: []string{"three@example.com"}, Err: errors.New("mailbox full")}
+	deliveries[0].NextAttempt = time.Now()
+	_ = mockApp.Outbox.Save(deliveries[0])
+	RetryFailedDeliveries(mockApp)
+
+	if len(sentTo) != 2 || !slices.Equal(sentTo[1], []string{"two@example.com", "three@example.com"}) {
+		t.Fatalf("Expected the retry to go to the undelivered recipients only, got %v", sentTo)
+	}
+	deliveries, _ = mockApp.Outbox.List()
+	if len(deliveries) != 1 || !slices.Equal(deliveries[0].Recipients, []string{"three@example.com"}) {
+		t.Errorf("Expected the recipients still waiting to be kept, got %+v", deliveries)
+	}
+}
+
+func TestDryRunCategoryDigestJob(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	mockApp := app.NewApp(
+		app.WithConfig(&config.Config{Digest: config.ConfigDigest{
+			Email: config.ConfigDigestEmail{To: []string{"to@example.com"}, Cc: []string{"cc@example.com"}, From: "from@example.com"},
+		}}),
+		app.WithMinifluxClientService(&testutil.MockMinifluxClient{
+			MarkEntriesAsReadFunc: func(entryIDs []int64) error {
+				t.Error("Expected entries not to be marked as read during a dry run")
+				return nil
+			},
+		}),
+		app.WithDigestService(&testutil.MockDigestService{
+			BuildDigestDataFunc: func(category *miniflux.Category, entries *miniflux.Entries, icons map[int64]*models.FeedIcon, groupBy digest.GroupingType, minifluxHost string) *models.HTMLTemplateData {
+				return &models.HTMLTemplateData{Entries: entries, Category: category}
+			},
+		}),
+		app.WithArchiveService(&testutil.MockArchiveService{
+			MakeArchiveHTMLFunc: func(data *models.HTMLTemplateData, compress bool) (*os.File, error) {
+				return os.CreateTemp(t.TempDir(), "test-archive-*.html")
+			},
+		}),
+		app.WithEmailService(&testutil.MockEmailService{
+			SendFunc: func(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error {
+				t.Error("Expected no email to be sent during a dry run")
+				return nil
+			},
+		}),
+	)
+	data := &app.RawCategoryData{
+		Category: &miniflux.Category{ID: 3, Title: "Security"},
+		Entries:  &miniflux.Entries{{ID: 11}, {ID: 12}},
+	}
+
+	var buf bytes.Buffer
+	if err := DryRunCategoryDigestJob(mockApp, data, &buf); err != nil {
+		t.Fatalf("DryRunCategoryDigestJob failed: %v", err)
+	}
+
+	for _, want := range []string{"Security", "To: to@example.com, cc@example.com", "Would mark as read: [11 12]"} {
+		if !bytes.Contains(buf.Bytes(), []byte(want)) {
+			t.Errorf("Expected dry run report to contain %q, got %q", want, buf.String())
+		}
+	}
+
+	suppressions, err := unsubscribe.NewFileSuppressionList(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create suppression list: %v", err)
+	}
+	if err := suppressions.Suppress(3, "cc@example.com"); err != nil {
+		t.Fatalf("Suppress failed: %v", err)
+	}
+	mockApp.Suppressions = suppressions
+
+	buf.Reset()
+	if err := DryRunCategoryDigestJob(mockApp, data, &buf); err != nil {
+		t.Fatalf("DryRunCategoryDigestJob failed: %v", err)
+	}
+	if want := "To: to@example.com\n"; !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("Expected dry run report to leave out unsubscribed recipients, got %q", buf.String())
+	}
+}
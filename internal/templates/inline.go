@@ -0,0 +1,264 @@
+package templates
+
+import (
+	"bytes"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	cssCommentPattern     = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	simpleSelectorPattern = regexp.MustCompile(`^([a-z][a-z0-9]*)?((?:\.[A-Za-z0-9_-]+)*)$`)
+
+	// unsupportedSelectors holds the selectors that were already logged, so
+	// every email sent does not log them again.
+	unsupportedSelectors sync.Map
+)
+
+type cssDeclaration struct {
+	property string
+	value    string
+}
+
+type cssRule struct {
+	tag          string
+	classes      []string
+	specificity  int
+	order        int
+	declarations []cssDeclaration
+}
+
+func (r *cssRule) matches(node *html.Node) bool {
+	if r.tag != "" && r.tag != node.Data {
+		return false
+	}
+	classes := strings.Fields(attr(node, "class"))
+	for _, class := range r.classes {
+		if !slices.Contains(classes, class) {
+			return false
+		}
+	}
+	return true
+}
+
+// InlineCSS moves the rules of the <style> elements of an HTML document into
+// the style attribute of every element they match, since most mail clients
+// drop or ignore <style> blocks. Only tag, class and tag.class selectors are
+// inlined, anything else (at-rules, pseudo-classes, combinators) is left in
+// the <style> element for the clients that support it, and logged once since
+// most mail clients will not apply it.
+func InlineCSS(document []byte) ([]byte, error) {
+	root, err := html.Parse(bytes.NewReader(document))
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []*cssRule
+	var styles []*html.Node
+	walk(root, func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "style" {
+			styles = append(styles, node)
+		}
+	})
+
+	for _, style := range styles {
+		var css strings.Builder
+		for child := style.FirstChild; child != nil; child = child.NextSibling {
+			css.WriteString(child.Data)
+		}
+
+		var remaining string
+		rules, remaining = parseCSS(css.String(), rules)
+		if strings.TrimSpace(remaining) == "" {
+			style.Parent.RemoveChild(style)
+			continue
+		}
+		for style.FirstChild != nil {
+			style.RemoveChild(style.FirstChild)
+		}
+		style.AppendChild(&html.Node{Type: html.TextNode, Data: remaining})
+	}
+
+	slices.SortStableFunc(rules, func(a, b *cssRule) int {
+		if a.specificity != b.specificity {
+			return a.specificity - b.specificity
+		}
+		return a.order - b.order
+	})
+
+	walk(root, func(node *html.Node) {
+		if node.Type != html.ElementNode {
+			return
+		}
+
+		var declarations []cssDeclaration
+		for _, rule := range rules {
+			if rule.matches(node) {
+				declarations = mergeDeclarations(declarations, rule.declarations)
+			}
+		}
+		if len(declarations) == 0 {
+			return
+		}
+
+		declarations = mergeDeclarations(declarations, parseDeclarations(attr(node, "style")))
+		setAttr(node, "style", formatDeclarations(declarations))
+	})
+
+	var buf bytes.Buffer
+	if err := html.Render(&buf, root); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseCSS appends the inlinable rules of a stylesheet to rules and returns
+// the rules it could not inline as CSS text.
+func parseCSS(css string, rules []*cssRule) ([]*cssRule, string) {
+	css = cssCommentPattern.ReplaceAllString(css, "")
+	var remaining strings.Builder
+
+	for {
+		open := strings.Index(css, "{")
+		if open < 0 {
+			break
+		}
+		selectors := strings.TrimSpace(css[:open])
+
+		end := blockEnd(css, open)
+		body := css[open+1 : end]
+		block := css[:min(end+1, len(css))]
+		css = css[min(end+1, len(css)):]
+
+		if strings.HasPrefix(selectors, "@") {
+			remaining.WriteString(block)
+			continue
+		}
+
+		declarations := parseDeclarations(body)
+		var unsupported []string
+		for _, selector := range strings.Split(selectors, ",") {
+			selector = strings.TrimSpace(selector)
+			match := simpleSelectorPattern.FindStringSubmatch(selector)
+			if selector == "" || match == nil {
+				unsupported = append(unsupported, selector)
+				logUnsupportedSelector(selector)
+				continue
+			}
+
+			var classes []string
+			if match[2] != "" {
+				classes = strings.Split(match[2][1:], ".")
+			}
+			specificity := len(classes) * 10
+			if match[1] != "" {
+				specificity++
+			}
+			rules = append(rules, &cssRule{
+				tag:          match[1],
+				classes:      classes,
+				specificity:  specificity,
+				order:        len(rules),
+				declarations: declarations,
+			})
+		}
+
+		if len(unsupported) > 0 {
+			remaining.WriteString(strings.Join(unsupported, ", ") + " {" + body + "}\n")
+		}
+	}
+
+	return rules, remaining.String()
+}
+
+func logUnsupportedSelector(selector string) {
+	if _, logged := unsupportedSelectors.LoadOrStore(selector, true); !logged {
+		log.Printf("CSS selector %q cannot be inlined and only applies in mail clients supporting <style>\n", selector)
+	}
+}
+
+// blockEnd returns the index of the brace closing the block opened at open.
+func blockEnd(css string, open int) int {
+	depth := 0
+	for i := open; i < len(css); i++ {
+		switch css[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(css)
+}
+
+func parseDeclarations(style string) []cssDeclaration {
+	var declarations []cssDeclaration
+	for _, declaration := range strings.Split(style, ";") {
+		property, value, ok := strings.Cut(declaration, ":")
+		property = strings.ToLower(strings.TrimSpace(property))
+		value = strings.TrimSpace(value)
+		if !ok || property == "" || value == "" {
+			continue
+		}
+		declarations = append(declarations, cssDeclaration{property: property, value: value})
+	}
+	return declarations
+}
+
+// mergeDeclarations applies overrides on top of declarations, replacing the
+// value of properties that are already set.
+func mergeDeclarations(declarations, overrides []cssDeclaration) []cssDeclaration {
+	for _, override := range overrides {
+		i := slices.IndexFunc(declarations, func(d cssDeclaration) bool {
+			return d.property == override.property
+		})
+		if i >= 0 {
+			declarations[i].value = override.value
+		} else {
+			declarations = append(declarations, override)
+		}
+	}
+	return declarations
+}
+
+func formatDeclarations(declarations []cssDeclaration) string {
+	parts := make([]string, len(declarations))
+	for i, declaration := range declarations {
+		parts[i] = declaration.property + ": " + declaration.value
+	}
+	return strings.Join(parts, "; ") + ";"
+}
+
+func walk(node *html.Node, fn func(*html.Node)) {
+	fn(node)
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, fn)
+	}
+}
+
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func setAttr(node *html.Node, key, value string) {
+	for i, a := range node.Attr {
+		if a.Key == key {
+			node.Attr[i].Val = value
+			return
+		}
+	}
+	node.Attr = append(node.Attr, html.Attribute{Key: key, Val: value})
+}
@@ -0,0 +1,100 @@
+package templates
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestInlineCSS(t *testing.T) {
+	document := `<html><head><style>
+		/* base */
+		a { color: blue; text-decoration: none; }
+		.note { color: gray; }
+		a.note { font-weight: bold; }
+		a:hover { color: red; }
+		@media (max-width: 600px) { .note { font-size: 12px; } }
+	</style></head><body>
+		<a href="#" class="note" style="color: green">link</a>
+		<p class="note other">text</p>
+		<span>plain</span>
+	</body></html>`
+
+	html, err := InlineCSS([]byte(document))
+	if err != nil {
+		t.Fatalf("InlineCSS failed: %v", err)
+	}
+	result := string(html)
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"inline style wins over rules", `<a href="#" class="note" style="color: green; text-decoration: none; font-weight: bold;">`},
+		{"class rules match elements with several classes", `<p class="note other" style="color: gray;">`},
+		{"unmatched elements are left alone", `<span>plain</span>`},
+		{"unsupported selectors are kept", `a:hover {`},
+		{"at-rules are kept", `@media (max-width: 600px)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(result, tt.want) {
+				t.Errorf("Expected %q in %s", tt.want, result)
+			}
+		})
+	}
+
+	if strings.Contains(result, "/* base */") {
+		t.Error("Expected CSS comments to be removed")
+	}
+}
+
+func TestInlineCSS_RemovesEmptyStyle(t *testing.T) {
+	html, err := InlineCSS([]byte(`<html><head><style>p { margin: 0; }</style></head><body><p>text</p></body></html>`))
+	if err != nil {
+		t.Fatalf("InlineCSS failed: %v", err)
+	}
+	if strings.Contains(string(html), "<style") {
+		t.Errorf("Expected fully inlined <style> to be removed, got %s", html)
+	}
+	if !strings.Contains(string(html), `<p style="margin: 0;">`) {
+		t.Errorf("Expected p style to be inlined, got %s", html)
+	}
+}
+
+func TestInlineCSS_EmailStylesheet(t *testing.T) {
+	data := sampleData()
+	var buf bytes.Buffer
+	if err := EmailHTMLTemplate.Execute(&buf, EmailTemplateData{HTMLTemplateData: data, Summary: data.Summary, Attached: true}); err != nil {
+		t.Fatalf("Failed to execute EmailHTMLTemplate: %v", err)
+	}
+	root, err := html.Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Failed to parse email: %v", err)
+	}
+
+	var css strings.Builder
+	walk(root, func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == "style" {
+			for child := node.FirstChild; child != nil; child = child.NextSibling {
+				css.WriteString(child.Data)
+			}
+		}
+	})
+	rules, remaining := parseCSS(css.String(), nil)
+	if strings.TrimSpace(remaining) != "" {
+		t.Errorf("Expected every rule of the email stylesheet to be inlined, left %q", remaining)
+	}
+
+	for _, rule := range rules {
+		matched := false
+		walk(root, func(node *html.Node) {
+			matched = matched || node.Type == html.ElementNode && rule.matches(node)
+		})
+		if !matched {
+			t.Errorf("Expected rule %s%s to match an element of the email", rule.tag, strings.Join(append([]string{""}, rule.classes...), "."))
+		}
+	}
+}
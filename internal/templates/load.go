@@ -0,0 +1,201 @@
+package templates
+
+import (
+	"errors"
+	"fmt"
+	htmlTemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	textTemplate "text/template"
+	"time"
+
+	miniflux "miniflux.app/v2/client"
+
+	"miniflux-digest/internal/models"
+)
+
+const (
+	archiveTemplateName   = "entries.gohtml"
+	emailTemplateName     = "email.gotxt"
+	emailHTMLTemplateName = "email.gohtml"
+)
+
+// source is the text of a template and where it was read from, for errors.
+type source struct {
+	name   string
+	origin string
+	text   string
+}
+
+var funcs = htmlTemplate.FuncMap{
+	"htmlEscape": func(s string) htmlTemplate.HTML {
+		return htmlTemplate.HTML(s)
+	},
+	"feedIconCID": func(feedID int64) htmlTemplate.URL {
+		return htmlTemplate.URL("cid:" + FeedIconCID(feedID))
+	},
+}
+
+// Load parses the templates, replacing the embedded ones with the files of the
+// same name in dir when it is set. Every other *.gohtml or *.gotxt file in
+// dir is added as a partial to the HTML or text templates. bodyFiles are the
+// digest.email.body_template_file paths, parsed like email.gotxt with the
+// same partials, and subjects the digest.email.subject templates. The
+// templates are executed with sample data, so mistakes are reported when
+// loading instead of when a digest is sent.
+func Load(dir string, bodyFiles, subjects []string) error {
+	sources := make(map[string]source)
+	for _, name := range []string{archiveTemplateName, emailTemplateName, emailHTMLTemplateName} {
+		text, err := embedFS.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		sources[name] = source{name: name, origin: "embedded " + name, text: string(text)}
+	}
+
+	var htmlPartials, textPartials []source
+	if dir != "" {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return fmt.Errorf("failed to read templates directory: %w", err)
+		}
+
+		for _, entry := range entries {
+			ext := filepath.Ext(entry.Name())
+			if entry.IsDir() || (ext != ".gohtml" && ext != ".gotxt") {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			text, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("failed to read template %s: %w", path, err)
+			}
+
+			s := source{name: entry.Name(), origin: path, text: string(text)}
+			_, override := sources[s.name]
+			switch {
+			case override:
+				sources[s.name] = s
+			case ext == ".gohtml":
+				htmlPartials = append(htmlPartials, s)
+			default:
+				textPartials = append(textPartials, s)
+			}
+		}
+	}
+
+	archive, err := parseHTML(sources[archiveTemplateName], htmlPartials)
+	if err != nil {
+		return err
+	}
+	emailHTML, err := parseHTML(sources[emailHTMLTemplateName], htmlPartials)
+	if err != nil {
+		return err
+	}
+	email, err := parseText(sources[emailTemplateName], textPartials)
+	if err != nil {
+		return err
+	}
+
+	data := sampleData()
+	emailData := EmailTemplateData{HTMLTemplateData: data, URL: "https://example.com/archive/1/digest.html", Summary: data.Summary, Attached: true}
+	errs := []error{
+		check(sources[archiveTemplateName], archive.Execute(io.Discard, data)),
+		check(sources[emailHTMLTemplateName], emailHTML.Execute(io.Discard, emailData)),
+		check(sources[emailTemplateName], email.Execute(io.Discard, emailData)),
+	}
+
+	bodies := make(map[string]*textTemplate.Template)
+	for _, path := range bodyFiles {
+		if _, ok := bodies[path]; ok {
+			continue
+		}
+		text, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read template %s: %w", path, err)
+		}
+		s := source{name: filepath.Base(path), origin: path, text: string(text)}
+		body, err := parseText(s, textPartials)
+		if err != nil {
+			return err
+		}
+		errs = append(errs, check(s, body.Execute(io.Discard, emailData)))
+		bodies[path] = body
+	}
+
+	for _, subject := range subjects {
+		if _, err := RenderSubject(subject, emailData); err != nil {
+			errs = append(errs, fmt.Errorf("invalid subject template %q: %w", subject, err))
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	ArchiveTemplate, EmailHTMLTemplate, EmailTemplate = archive, emailHTML, email
+	bodyTemplates = bodies
+	return nil
+}
+
+func check(s source, err error) error {
+	if err != nil {
+		return fmt.Errorf("invalid template %s: %w", s.origin, err)
+	}
+	return nil
+}
+
+func parseHTML(main source, partials []source) (*htmlTemplate.Template, error) {
+	tmpl, err := htmlTemplate.New(main.name).Funcs(funcs).Parse(main.text)
+	if err != nil {
+		return nil, check(main, err)
+	}
+	for _, partial := range partials {
+		if _, err := tmpl.New(partial.name).Parse(partial.text); err != nil {
+			return nil, check(partial, err)
+		}
+	}
+	return tmpl, nil
+}
+
+func parseText(main source, partials []source) (*textTemplate.Template, error) {
+	tmpl, err := textTemplate.New(main.name).Parse(main.text)
+	if err != nil {
+		return nil, check(main, err)
+	}
+	for _, partial := range partials {
+		if _, err := tmpl.New(partial.name).Parse(partial.text); err != nil {
+			return nil, check(partial, err)
+		}
+	}
+	return tmpl, nil
+}
+
+// sampleData is a digest with every field set, used to check templates.
+func sampleData() models.HTMLTemplateData {
+	feed := &miniflux.Feed{ID: 1, Title: "Example Feed", SiteURL: "https://example.com"}
+	entry := &miniflux.Entry{
+		ID:          1,
+		FeedID:      feed.ID,
+		Feed:        feed,
+		Title:       "Example entry",
+		URL:         "https://example.com/entry",
+		CommentsURL: "https://example.com/entry#comments",
+		Content:     "<p>Example content</p>",
+		Date:        time.Now(),
+	}
+	entries := miniflux.Entries{entry}
+
+	return models.HTMLTemplateData{
+		Category:         &miniflux.Category{ID: 1, Title: "Example"},
+		Entries:          &entries,
+		GeneratedDate:    time.Now(),
+		FeedIcons:        []*models.FeedIcon{{FeedID: feed.ID, Data: "image/png;base64,AAAA"}},
+		EntryGroups:      []*models.EntryGroup{{Title: "Today", Entries: []*models.Entry{{Entry: entry, TLDR: "Example TL;DR"}}}},
+		Summary:          "Example summary",
+		MinifluxHost:     "https://miniflux.example.com",
+		RemainingEntries: 1,
+	}
+}
@@ -1,45 +1,97 @@
 package templates
 
 import (
+	"bytes"
 	"embed"
+	"fmt"
 	htmlTemplate "html/template"
 	"log"
 	"miniflux-digest/internal/models"
+	"strings"
 	textTemplate "text/template"
 )
 
+// DefaultSubject is the email subject template used when
+// digest.email.subject is not set.
+const DefaultSubject = "[miniflux digest] {{ .Category.Title }}"
+
 type EmailTemplateData struct {
 	models.HTMLTemplateData
 	URL string
 	Summary string
+	Attached bool
 }
 
-//go:embed *.gohtml *.gotxt
-var embedFS embed.FS
+// FeedIconCID returns the Content-ID a feed icon is embedded with in the HTML
+// email body.
+func FeedIconCID(feedID int64) string {
+	return fmt.Sprintf("feed-icon-%d", feedID)
+}
 
-var (
-	ArchiveTemplate *htmlTemplate.Template
-	EmailTemplate   *textTemplate.Template
-)
+// RenderEmailHTML renders the HTML email body with its styles inlined.
+func RenderEmailHTML(data EmailTemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := EmailHTMLTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
 
-func init() {
-	var err error
-	archiveTemplateName := "entries.gohtml"
-	emailTemplateName := "email.gotxt"
+	html, err := InlineCSS(buf.Bytes())
+	if err != nil {
+		return "", err
+	}
+	return string(html), nil
+}
 
-	ArchiveTemplate, err = htmlTemplate.New(archiveTemplateName).Funcs(htmlTemplate.FuncMap{
-		"htmlEscape": func(s string) htmlTemplate.HTML {
-			return htmlTemplate.HTML(s)
-		},
-	}).ParseFS(embedFS, archiveTemplateName)
+// ParseSubject parses an email subject template.
+func ParseSubject(subject string) (*textTemplate.Template, error) {
+	if subject == "" {
+		subject = DefaultSubject
+	}
+	return textTemplate.New("subject").Parse(subject)
+}
 
+// RenderSubject renders an email subject template on a single line.
+func RenderSubject(subject string, data EmailTemplateData) (string, error) {
+	tmpl, err := ParseSubject(subject)
 	if err != nil {
-		log.Fatalf("Error parsing archive template: %v", err)
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
 	}
+	return strings.Join(strings.Fields(buf.String()), " "), nil
+}
 
-	EmailTemplate, err = textTemplate.New(emailTemplateName).ParseFS(embedFS, emailTemplateName)
+// EmailBodyTemplate returns the text email body template loaded from path, or
+// the embedded EmailTemplate when path is empty.
+func EmailBodyTemplate(path string) (*textTemplate.Template, error) {
+	if path == "" {
+		return EmailTemplate, nil
+	}
+	body, ok := bodyTemplates[path]
+	if !ok {
+		return nil, fmt.Errorf("email body template %s was not loaded", path)
+	}
+	return body, nil
+}
 
-	if err != nil {
-		log.Fatalf("Error parsing email template: %v", err)
+//go:embed *.gohtml *.gotxt
+var embedFS embed.FS
+
+var (
+	ArchiveTemplate   *htmlTemplate.Template
+	EmailTemplate     *textTemplate.Template
+	EmailHTMLTemplate *htmlTemplate.Template
+
+	// bodyTemplates holds the digest.email.body_template_file templates
+	// keyed by path.
+	bodyTemplates map[string]*textTemplate.Template
+)
+
+func init() {
+	if err := Load("", nil, nil); err != nil {
+		log.Fatalf("Error parsing templates: %v", err)
 	}
 }
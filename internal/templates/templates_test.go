@@ -2,9 +2,14 @@ package templates
 
 import (
 	"bytes"
+	"fmt"
 	"miniflux-digest/internal/models"
 	"miniflux-digest/internal/testutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestTemplates(t *testing.T) {
@@ -52,3 +57,286 @@ func TestEmailTemplateExecution(t *testing.T) {
 		t.Error("EmailTemplate execution resulted in empty output")
 	}
 }
+
+func TestArchiveTemplateRemainingEntries(t *testing.T) {
+	data := models.HTMLTemplateData{
+		Category:         testutil.NewMockCategory(),
+		Entries:          testutil.NewMockEntries(),
+		FeedIcons:        testutil.NewMockFeedIcons(),
+		RemainingEntries: 42,
+	}
+	var buf bytes.Buffer
+	if err := ArchiveTemplate.Execute(&buf, data); err != nil {
+		t.Fatalf("Failed to execute ArchiveTemplate: %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("42 more entries not shown")) {
+		t.Error("Expected ArchiveTemplate to include the remaining entries notice")
+	}
+}
+
+func TestTemplatesEntrySummaries(t *testing.T) {
+	entries := testutil.NewMockEntries()
+	entry := (*entries)[0]
+	group := &models.EntryGroup{Title: "Today"}
+	for _, e := range *entries {
+		group.Entries = append(group.Entries, &models.Entry{Entry: e})
+	}
+	group.Entries[0].TLDR = "Short & sweet TL;DR"
+	data := models.HTMLTemplateData{
+		Category:    testutil.NewMockCategory(),
+		Entries:     entries,
+		FeedIcons:   testutil.NewMockFeedIcons(),
+		EntryGroups: []*models.EntryGroup{group},
+	}
+
+	var buf bytes.Buffer
+	if err := ArchiveTemplate.Execute(&buf, data); err != nil {
+		t.Fatalf("Failed to execute ArchiveTemplate: %v", err)
+	}
+	if !strings.Contains(buf.String(), `<span class="entry-tldr">Short &amp; sweet TL;DR</span>`) {
+		t.Error("Expected ArchiveTemplate to include the entry TL;DR")
+	}
+	if strings.Count(buf.String(), `<span class="entry-tldr">`) != 1 {
+		t.Error("Expected only the summarized entry to have a TL;DR")
+	}
+
+	buf.Reset()
+	if err := EmailTemplate.Execute(&buf, &EmailTemplateData{HTMLTemplateData: data}); err != nil {
+		t.Fatalf("Failed to execute EmailTemplate: %v", err)
+	}
+	want := fmt.Sprintf("- %s\n  Short & sweet TL;DR\n  %s\n", entry.Title, entry.URL)
+	if !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected EmailTemplate to include %q, got:\n%s", want, buf.String())
+	}
+
+	html, err := RenderEmailHTML(EmailTemplateData{HTMLTemplateData: data})
+	if err != nil {
+		t.Fatalf("RenderEmailHTML() error = %v", err)
+	}
+	if !strings.Contains(html, "Short &amp; sweet TL;DR</div>") {
+		t.Error("Expected the HTML email to include the entry TL;DR")
+	}
+}
+
+func TestRenderEmailHTML(t *testing.T) {
+	entry := (*testutil.NewMockEntries())[0]
+	data := EmailTemplateData{
+		HTMLTemplateData: models.HTMLTemplateData{
+			Category:  testutil.NewMockCategory(),
+			Entries:   testutil.NewMockEntries(),
+			FeedIcons: []*models.FeedIcon{{FeedID: entry.FeedID, Data: "image/png;base64,AAAA"}},
+			EntryGroups: []*models.EntryGroup{
+				{Title: "Today", Entries: []*models.Entry{{Entry: entry}}},
+			},
+		},
+		URL: "https://example.com/archive/1/digest.html",
+	}
+
+	html, err := RenderEmailHTML(data)
+	if err != nil {
+		t.Fatalf("Failed to render email HTML: %v", err)
+	}
+	if strings.Contains(html, "<style") || strings.Contains(html, "<script") {
+		t.Error("Expected the email HTML to only use inline styles")
+	}
+	if !strings.Contains(html, `<td class="entry" style="padding: 12px;`) {
+		t.Error("Expected entry styles to be inlined")
+	}
+	if !strings.Contains(html, `src="cid:`+FeedIconCID(entry.FeedID)+`"`) {
+		t.Error("Expected feed icons to reference their Content-ID")
+	}
+	if !strings.Contains(html, data.URL) {
+		t.Error("Expected the email HTML to link to the archive")
+	}
+}
+
+func TestRenderSubject(t *testing.T) {
+	data := EmailTemplateData{
+		HTMLTemplateData: models.HTMLTemplateData{
+			Category:      testutil.NewMockCategory(),
+			Entries:       testutil.NewMockEntries(),
+			GeneratedDate: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+		Summary: "Quiet day",
+	}
+
+	tests := []struct {
+		name    string
+		subject string
+		want    string
+	}{
+		{"default", "", "[miniflux digest] Test Category"},
+		{"custom", `{{ .Category.Title }}: {{ .EntryCount }} entries on {{ .GeneratedDate.Format "2006-01-02" }}`, fmt.Sprintf("Test Category: %d entries on 2024-03-09", len(*data.Entries))},
+		{"single line", "{{ .Summary }}\n  digest", "Quiet day digest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderSubject(tt.subject, data)
+			if err != nil {
+				t.Fatalf("RenderSubject failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("RenderSubject() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := RenderSubject("{{ .Missing }}", data); err == nil {
+		t.Error("Expected an error for an unknown field")
+	}
+}
+
+func TestEmailBodyTemplate(t *testing.T) {
+	t.Cleanup(func() {
+		if err := Load("", nil, nil); err != nil {
+			t.Fatalf("Failed to restore embedded templates: %v", err)
+		}
+	})
+
+	tmpl, err := EmailBodyTemplate("")
+	if err != nil || tmpl != EmailTemplate {
+		t.Errorf("Expected the embedded template without a path, got %v, %v", tmpl, err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "footer.gotxt"), []byte(`{{ define "footer" }}: {{ .URL }}{{ end }}`), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "body.gotxt")
+	if err := os.WriteFile(path, []byte(`{{ .EntryCount }} new in {{ .Category.Title }}{{ template "footer" . }}`), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	if _, err := EmailBodyTemplate(path); err == nil {
+		t.Error("Expected an error for a body template that was not loaded")
+	}
+	if err := Load(dir, []string{path}, nil); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	tmpl, err = EmailBodyTemplate(path)
+	if err != nil {
+		t.Fatalf("EmailBodyTemplate failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	data := EmailTemplateData{
+		HTMLTemplateData: models.HTMLTemplateData{Category: testutil.NewMockCategory(), Entries: testutil.NewMockEntries()},
+		URL:              "https://example.com/digest.html",
+	}
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("Failed to execute template: %v", err)
+	}
+	want := fmt.Sprintf("%d new in Test Category: https://example.com/digest.html", len(*data.Entries))
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Cleanup(func() {
+		if err := Load("", nil, nil); err != nil {
+			t.Fatalf("Failed to restore embedded templates: %v", err)
+		}
+	})
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"email.gotxt":    `{{ template "footer.gotxt" . }}`,
+		"footer.gotxt":   "Branded footer for {{ .Category.Title }}",
+		"entries.gohtml": `<html><body>{{ template "header" . }}</body></html>`,
+		"header.gohtml":  `{{ define "header" }}<h1>Acme {{ .Category.Title }}</h1>{{ end }}`,
+		"notes.txt":      "{{ not a template",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	if err := Load(dir, nil, nil); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	data := EmailTemplateData{HTMLTemplateData: models.HTMLTemplateData{Category: testutil.NewMockCategory(), Entries: testutil.NewMockEntries()}}
+	var buf bytes.Buffer
+	if err := EmailTemplate.Execute(&buf, data); err != nil || buf.String() != "Branded footer for Test Category" {
+		t.Errorf("Expected the text override with its partial, got %q, %v", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := ArchiveTemplate.Execute(&buf, data.HTMLTemplateData); err != nil || !strings.Contains(buf.String(), "<h1>Acme Test Category</h1>") {
+		t.Errorf("Expected the archive override with its partial, got %q, %v", buf.String(), err)
+	}
+
+	html, err := RenderEmailHTML(data)
+	if err != nil || !strings.Contains(html, "Test Category") {
+		t.Errorf("Expected the embedded HTML email template to be kept, got %v", err)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	t.Cleanup(func() {
+		if err := Load("", nil, nil); err != nil {
+			t.Fatalf("Failed to restore embedded templates: %v", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"syntax error", "email.gohtml", "{{ if .Summary }}"},
+		{"unknown field", "entries.gohtml", "{{ .Category.Name }}"},
+		{"broken partial", "header.gotxt", "{{ end }}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write template: %v", err)
+			}
+
+			before := ArchiveTemplate
+			err := Load(dir, nil, nil)
+			if err == nil || !strings.Contains(err.Error(), path) {
+				t.Errorf("Expected an error naming %s, got %v", path, err)
+			}
+			if ArchiveTemplate != before {
+				t.Error("Expected the templates to be kept when loading fails")
+			}
+		})
+	}
+
+	if err := Load(filepath.Join(t.TempDir(), "missing"), nil, nil); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+
+	body := filepath.Join(t.TempDir(), "body.gotxt")
+	if err := os.WriteFile(body, []byte("{{ .Category.Name }}"), 0644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+	if err := Load("", []string{body}, nil); err == nil || !strings.Contains(err.Error(), body) {
+		t.Errorf("Expected an error naming %s, got %v", body, err)
+	}
+	if err := Load("", []string{filepath.Join(t.TempDir(), "missing.gotxt")}, nil); err == nil {
+		t.Error("Expected an error for a missing body template")
+	}
+}
+
+func TestLoad_InvalidSubject(t *testing.T) {
+	t.Cleanup(func() {
+		if err := Load("", nil, nil); err != nil {
+			t.Fatalf("Failed to restore embedded templates: %v", err)
+		}
+	})
+
+	if err := Load("", nil, []string{"{{ .EntryCount }} new in {{ .Category.Title }}"}); err != nil {
+		t.Errorf("Expected a valid subject to load, got %v", err)
+	}
+	subject := "{{ .Nope }} digest"
+	if err := Load("", nil, []string{subject}); err == nil || !strings.Contains(err.Error(), subject) {
+		t.Errorf("Expected an error naming the subject %q, got %v", subject, err)
+	}
+}
@@ -14,10 +14,12 @@ import (
 type MockMinifluxClient struct {
 	app.MinifluxClientService
 	MarkAsReadFunc func(categoryID int64) error
+	MarkEntriesAsReadFunc func(entryIDs []int64) error
 	CategoriesFunc func() ([]*miniflux.Category, error)
 	CategoryEntriesFunc func(categoryID int64, filter *miniflux.Filter) (*miniflux.Entries, error)
 	CategoryFeedsFunc func(categoryID int64) ([]*miniflux.Feed, error)
 	FeedIconFunc func(feedID int64) (*miniflux.FeedIcon, error)
+	FetchRawCategoryDataFunc func(categoryID int64) (*app.RawCategoryData, error)
 	StreamAllCategoryDataFunc func() <-chan *app.RawCategoryData
 }
 
@@ -28,6 +30,13 @@ func (m *MockMinifluxClient) MarkCategoryAsRead(categoryID int64) error {
 	return nil
 }
 
+func (m *MockMinifluxClient) MarkEntriesAsRead(entryIDs []int64) error {
+	if m.MarkEntriesAsReadFunc != nil {
+		return m.MarkEntriesAsReadFunc(entryIDs)
+	}
+	return nil
+}
+
 func (m *MockMinifluxClient) Categories() ([]*miniflux.Category, error) {
 	if m.CategoriesFunc != nil {
 		return m.CategoriesFunc()
@@ -56,7 +65,12 @@ func (m *MockMinifluxClient) FeedIcon(feedID int64) (*miniflux.FeedIcon, error)
 	return nil, nil
 }
 
-
+func (m *MockMinifluxClient) FetchRawCategoryData(categoryID int64) (*app.RawCategoryData, error) {
+	if m.FetchRawCategoryDataFunc != nil {
+		return m.FetchRawCategoryDataFunc(categoryID)
+	}
+	return nil, nil
+}
 
 func (m *MockMinifluxClient) StreamAllCategoryData() <-chan *app.RawCategoryData {
 	if m.StreamAllCategoryDataFunc != nil {
@@ -81,12 +95,20 @@ func (m *MockArchiveService) CleanArchive(maxAge time.Duration) {}
 type MockEmailService struct {
 	app.EmailService
 	SendFunc func(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error
+	CloseFunc func() error
 }
 
 func (m *MockEmailService) Send(cfg *config.Config, file *os.File, data *models.HTMLTemplateData) error {
 	return m.SendFunc(cfg, file, data)
 }
 
+func (m *MockEmailService) Close() error {
+	if m.CloseFunc != nil {
+		return m.CloseFunc()
+	}
+	return nil
+}
+
 type MockDigestService struct {
 	app.DigestService
 	BuildDigestDataFunc func(category *miniflux.Category, entries *miniflux.Entries, icons map[int64]*models.FeedIcon, groupBy digest.GroupingType, minifluxHost string) *models.HTMLTemplateData
@@ -0,0 +1,99 @@
+package unsubscribe
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"miniflux-digest/internal/app"
+	"miniflux-digest/internal/utils"
+)
+
+type Suppression struct {
+	CategoryID int64     `json:"category_id"`
+	Email      string    `json:"email"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type suppressionKey struct {
+	categoryID int64
+	email      string
+}
+
+// FileSuppressionList keeps the recipients that unsubscribed in a JSON file,
+// so opt-outs survive restarts.
+type FileSuppressionList struct {
+	path         string
+	mu           sync.Mutex
+	suppressions []Suppression
+	index        map[suppressionKey]bool
+}
+
+var _ app.SuppressionList = (*FileSuppressionList)(nil)
+
+func NewFileSuppressionList(dir string) (*FileSuppressionList, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create suppression list directory: %w", err)
+	}
+
+	l := &FileSuppressionList{
+		path:  filepath.Join(dir, "suppressions.json"),
+		index: make(map[suppressionKey]bool),
+	}
+
+	data, err := os.ReadFile(l.path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read suppression list: %w", err)
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &l.suppressions); err != nil {
+			return nil, fmt.Errorf("failed to decode suppression list %s: %w", l.path, err)
+		}
+	}
+
+	for _, s := range l.suppressions {
+		l.index[suppressionKey{s.CategoryID, NormalizeEmail(s.Email)}] = true
+	}
+	return l, nil
+}
+
+func (l *FileSuppressionList) Suppress(categoryID int64, email string) error {
+	key := suppressionKey{categoryID, NormalizeEmail(email)}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.index[key] {
+		return nil
+	}
+
+	suppressions := append(l.suppressions, Suppression{CategoryID: key.categoryID, Email: key.email, CreatedAt: time.Now()})
+	if err := l.write(suppressions); err != nil {
+		return err
+	}
+	l.suppressions = suppressions
+	l.index[key] = true
+	return nil
+}
+
+func (l *FileSuppressionList) IsSuppressed(categoryID int64, email string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.index[suppressionKey{categoryID, NormalizeEmail(email)}], nil
+}
+
+// write replaces the file atomically, so a crash never leaves a partially
+// written list behind.
+func (l *FileSuppressionList) write(suppressions []Suppression) error {
+	data, err := json.MarshalIndent(suppressions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return utils.WriteFileAtomic(l.path, data)
+}
@@ -0,0 +1,61 @@
+package unsubscribe
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSuppressionList(t *testing.T) {
+	dir := t.TempDir()
+	list, err := NewFileSuppressionList(dir)
+	if err != nil {
+		t.Fatalf("Failed to create suppression list: %v", err)
+	}
+
+	if err := list.Suppress(7, "Reader@Example.com"); err != nil {
+		t.Fatalf("Suppress failed: %v", err)
+	}
+	if err := list.Suppress(7, "reader@example.com"); err != nil {
+		t.Fatalf("Suppress failed: %v", err)
+	}
+
+	reopened, err := NewFileSuppressionList(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen suppression list: %v", err)
+	}
+
+	tests := []struct {
+		categoryID int64
+		email      string
+		want       bool
+	}{
+		{7, "reader@example.com", true},
+		{7, "READER@example.com", true},
+		{8, "reader@example.com", false},
+		{7, "other@example.com", false},
+	}
+	for _, tt := range tests {
+		suppressed, err := reopened.IsSuppressed(tt.categoryID, tt.email)
+		if err != nil {
+			t.Fatalf("IsSuppressed failed: %v", err)
+		}
+		if suppressed != tt.want {
+			t.Errorf("IsSuppressed(%d, %q) = %v, want %v", tt.categoryID, tt.email, suppressed, tt.want)
+		}
+	}
+
+	if len(reopened.suppressions) != 1 {
+		t.Errorf("Expected repeated unsubscribes to be stored once, got %d", len(reopened.suppressions))
+	}
+}
+
+func TestFileSuppressionListCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "suppressions.json"), []byte("{"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if _, err := NewFileSuppressionList(dir); err == nil {
+		t.Error("Expected an error for a corrupt suppression list")
+	}
+}
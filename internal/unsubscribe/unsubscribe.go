@@ -0,0 +1,66 @@
+package unsubscribe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Path is where the internal web server handles unsubscribe requests.
+const Path = "/unsubscribe"
+
+// Signer creates and verifies unsubscribe URLs. A URL is only valid for the
+// recipient and category it was created for.
+type Signer struct {
+	host   string
+	secret []byte
+}
+
+func NewSigner(host, secret string) *Signer {
+	return &Signer{host: strings.TrimRight(host, "/"), secret: []byte(secret)}
+}
+
+// NormalizeEmail is the form addresses are signed and suppressed in.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (s *Signer) token(categoryID int64, email string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	_, _ = fmt.Fprintf(mac, "%d:%s", categoryID, NormalizeEmail(email))
+	return mac.Sum(nil)
+}
+
+// URL returns the unsubscribe link of a recipient for a category.
+func (s *Signer) URL(categoryID int64, email string) string {
+	query := url.Values{
+		"category": {strconv.FormatInt(categoryID, 10)},
+		"email":    {NormalizeEmail(email)},
+		"token":    {base64.RawURLEncoding.EncodeToString(s.token(categoryID, email))},
+	}
+	return s.host + Path + "?" + query.Encode()
+}
+
+// Verify checks the query of an unsubscribe URL and returns the category and
+// recipient it was created for.
+func (s *Signer) Verify(query url.Values) (int64, string, bool) {
+	categoryID, err := strconv.ParseInt(query.Get("category"), 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+
+	token, err := base64.RawURLEncoding.DecodeString(query.Get("token"))
+	if err != nil {
+		return 0, "", false
+	}
+
+	email := NormalizeEmail(query.Get("email"))
+	if email == "" || !hmac.Equal(token, s.token(categoryID, email)) {
+		return 0, "", false
+	}
+	return categoryID, email, true
+}
@@ -0,0 +1,44 @@
+package unsubscribe
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSigner(t *testing.T) {
+	signer := NewSigner("https://digest.example.com/", "secret")
+
+	link := signer.URL(42, "Reader@Example.com")
+	if !strings.HasPrefix(link, "https://digest.example.com/unsubscribe?") {
+		t.Fatalf("Unexpected unsubscribe URL %s", link)
+	}
+
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("Failed to parse URL: %v", err)
+	}
+
+	categoryID, email, ok := signer.Verify(parsed.Query())
+	if !ok || categoryID != 42 || email != "reader@example.com" {
+		t.Errorf("Expected a valid link for category 42 and reader@example.com, got %d, %q, %v", categoryID, email, ok)
+	}
+
+	tampered := []func(url.Values){
+		func(q url.Values) { q.Set("category", "43") },
+		func(q url.Values) { q.Set("email", "other@example.com") },
+		func(q url.Values) { q.Set("token", "invalid") },
+		func(q url.Values) { q.Del("token") },
+	}
+	for i, tamper := range tampered {
+		query := parsed.Query()
+		tamper(query)
+		if _, _, ok := signer.Verify(query); ok {
+			t.Errorf("Expected tampered link %d to be rejected", i)
+		}
+	}
+
+	if _, _, ok := NewSigner("https://digest.example.com", "other").Verify(parsed.Query()); ok {
+		t.Error("Expected a link signed with another secret to be rejected")
+	}
+}